@@ -0,0 +1,275 @@
+package cds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aveva/connect-data-services/pkg/cds/assets"
+	"github.com/aveva/connect-data-services/pkg/cds/sds"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Searches the assets of a namespace.
+func AssetsQuery(d *CdsClient, namespaceId string, token string, query string, options SearchOptions) (*data.Frame, error) {
+	basePath := d.resource + "/api/" + d.apiVersion + "/tenants/" + url.QueryEscape(d.tenantId) + "/namespaces/" + url.QueryEscape(namespaceId)
+	path := (basePath + "/assets?query=" + url.QueryEscape(query))
+
+	results, truncated, err := searchPages[assets.Asset](d, token, path, nil, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// create property lists from assets list
+	ids := make([]string, len(results))
+	names := make([]string, len(results))
+	descriptions := make([]string, len(results))
+	assetTypeIds := make([]string, len(results))
+	streamReferences := make([]string, len(results))
+	metadata := make([]json.RawMessage, len(results))
+	for i, asset := range results {
+		ids[i] = asset.Id
+		names[i] = asset.Name
+		descriptions[i] = asset.Description
+		assetTypeIds[i] = asset.AssetTypeId
+
+		references := make([]string, len(asset.StreamReferences))
+		for j, reference := range asset.StreamReferences {
+			references[j] = reference.Name
+		}
+		streamReferences[i] = strings.Join(references, ",")
+
+		// a map of strings always marshals
+		metadata[i], _ = json.Marshal(assetMetadata(asset))
+	}
+
+	frame := data.NewFrame("response",
+		data.NewField("Id", nil, ids),
+		data.NewField("Name", nil, names),
+		data.NewField("Description", nil, descriptions),
+		data.NewField("AssetTypeId", nil, assetTypeIds),
+		data.NewField("StreamReferences", nil, streamReferences),
+		data.NewField("Metadata", nil, metadata),
+	)
+	setCatalogMeta(frame, truncated, len(ids))
+
+	return frame, nil
+}
+
+// Reads the data of the streams referenced by an asset, either between two indexes or
+// the last value. Each stream reference is returned as a frame, with the asset name,
+// the stream reference name and the asset metadata as labels of the value fields. The
+// metadata and tags of the streams are added as labels when requested. Stream references
+// that cannot be read are reported as notices of the first frame, and the query only fails
+// when no stream reference can be read.
+func AssetDataQuery(d *CdsClient, namespaceId string, token string, id string, startIndex string, endIndex string, lastValue bool, includeLabels bool) ([]*data.Frame, error) {
+	basePath := d.resource + "/api/" + d.apiVersion + "/tenants/" + url.QueryEscape(d.tenantId) + "/namespaces/" + url.QueryEscape(namespaceId)
+
+	var asset assets.Asset
+	err := cachedSdsRequest(d, token, basePath+"/assets/"+url.QueryEscape(id), nil, &asset)
+	if err != nil {
+		return nil, err
+	}
+
+	dataPath := "/Data?startIndex=" + url.QueryEscape(startIndex) + "&endIndex=" + url.QueryEscape(endIndex)
+	if lastValue {
+		dataPath = "/Data/Last"
+	}
+
+	frames := make([]*data.Frame, 0, len(asset.StreamReferences))
+	var failures []string
+	for _, reference := range asset.StreamReferences {
+		var validate func(sds.SdsType) error
+		if !lastValue {
+			validate = func(sdsType sds.SdsType) error {
+				return validateIndexRange(sdsType, startIndex, endIndex)
+			}
+		}
+
+		frame, sdsType, err := streamsDataFrame(d, namespaceId, token, reference.StreamId, dataPath, validate, includeLabels)
+		if err != nil {
+			log.DefaultLogger.Warn("Unable to read stream reference", "asset", asset.Id, "reference", reference.Name, "error", err.Error())
+			failures = append(failures, fmt.Sprintf("stream reference %s (stream %s) of asset %s: %s", reference.Name, reference.StreamId, asset.Name, err.Error()))
+			continue
+		}
+
+		labels := data.Labels{}
+		for key, value := range assetMetadata(asset) {
+			labels[key] = value
+		}
+		labels["asset"] = asset.Name
+		labels["streamReference"] = reference.Name
+
+		frame.Name = asset.Name + "." + reference.Name
+		labelValueFields(frame, sdsType, labels)
+		frames = append(frames, frame)
+	}
+
+	if len(failures) > 0 && len(frames) == 0 {
+		return nil, fmt.Errorf("unable to read %s", strings.Join(failures, "; "))
+	}
+	for _, failure := range failures {
+		frames[0].AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     "Unable to read " + failure,
+		})
+	}
+
+	return frames, nil
+}
+
+// Returns the metadata of an asset as text, keyed by the metadata name.
+func assetMetadata(asset assets.Asset) map[string]string {
+	metadata := make(map[string]string, len(asset.Metadata))
+	for _, item := range asset.Metadata {
+		name := item.Name
+		if name == "" {
+			name = item.Id
+		}
+		metadata[name] = item.String()
+	}
+
+	return metadata
+}
+
+// Upper bound on the assets of a status query, since the status of each asset takes a
+// request.
+const maxAssetStatuses = 1000
+
+// Number of asset status requests made at the same time by a status query.
+const assetStatusWorkers = 8
+
+// Creates a table of the status and metadata of assets, with one row per asset. Assets
+// are read by id, or searched when no id is given, up to maxAssetStatuses assets. Assets
+// without a status have empty status columns, and each metadata name is a column.
+func AssetStatusQuery(d *CdsClient, namespaceId string, token string, query string, id string, options SearchOptions) (*data.Frame, error) {
+	basePath := d.resource + "/api/" + d.apiVersion + "/tenants/" + url.QueryEscape(d.tenantId) + "/namespaces/" + url.QueryEscape(namespaceId)
+
+	var results []assets.Asset
+	truncated := false
+	if id != "" {
+		var asset assets.Asset
+		err := cachedSdsRequest(d, token, basePath+"/assets/"+url.QueryEscape(id), nil, &asset)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, asset)
+	} else {
+		// one more asset than is shown is read, to tell whether the results are truncated
+		var err error
+		options.maxResults = maxAssetStatuses + 1
+		results, truncated, err = searchPages[assets.Asset](d, token, basePath+"/assets?query="+url.QueryEscape(query), nil, options)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(results) > maxAssetStatuses {
+		log.DefaultLogger.Warn("Asset status results truncated", "assets", len(results))
+		results = results[:maxAssetStatuses]
+		truncated = true
+	}
+
+	assetStatuses, err := readAssetStatuses(d, token, basePath, results)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(results))
+	names := make([]string, len(results))
+	statuses := make([]*string, len(results))
+	displayNames := make([]*string, len(results))
+	lastUpdates := make([]*time.Time, len(results))
+	metadataFields := map[string]*data.Field{}
+	var metadataNames []string
+	for i, asset := range results {
+		ids[i] = asset.Id
+		names[i] = asset.Name
+
+		if status := assetStatuses[i]; status != nil {
+			statuses[i] = &status.Value.Status
+			displayNames[i] = &status.Value.DisplayName
+			if timestamp, err := parseSdsTime(status.Time); err == nil {
+				lastUpdates[i] = &timestamp
+			}
+		}
+
+		// add a column for each new metadata name, with nulls for the previous assets
+		for name, value := range assetMetadata(asset) {
+			field, ok := metadataFields[name]
+			if !ok {
+				field = data.NewField(name, nil, make([]*string, len(results)))
+				metadataFields[name] = field
+				metadataNames = append(metadataNames, name)
+			}
+			field.Set(i, &value)
+		}
+	}
+
+	frame := data.NewFrame("response",
+		data.NewField("Id", nil, ids),
+		data.NewField("Name", nil, names),
+		data.NewField("Status", nil, statuses),
+		data.NewField("StatusDisplayName", nil, displayNames),
+		data.NewField("LastUpdate", nil, lastUpdates),
+	)
+
+	sort.Strings(metadataNames)
+	for _, name := range metadataNames {
+		frame.Fields = append(frame.Fields, metadataFields[name])
+	}
+	setCatalogMeta(frame, truncated, len(ids))
+
+	return frame, nil
+}
+
+// Reads the status of each asset, with up to assetStatusWorkers requests at the same time.
+// Assets without a status have a nil status.
+func readAssetStatuses(d *CdsClient, token string, basePath string, results []assets.Asset) ([]*assets.AssetStatus, error) {
+	statuses := make([]*assets.AssetStatus, len(results))
+	errs := make([]error, len(results))
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, assetStatusWorkers)
+	for i, asset := range results {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func() {
+			defer wg.Done()
+			statuses[i], errs[i] = readAssetStatus(d, token, basePath, asset.Id)
+			<-workers
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return statuses, nil
+}
+
+// Reads the status of an asset. Assets without a status return 404, other failures fail
+// the query.
+func readAssetStatus(d *CdsClient, token string, basePath string, id string) (*assets.AssetStatus, error) {
+	body, err := SdsRequest(d, token, basePath+"/assets/"+url.QueryEscape(id)+"/status", nil)
+	if isNotFound(err) {
+		log.DefaultLogger.Debug("Asset has no status", "asset", id)
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read the status of asset %s: %w", id, err)
+	}
+
+	var status assets.AssetStatus
+	if err = json.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("unable to parse the status of asset %s: %w", id, err)
+	}
+
+	return &status, nil
+}
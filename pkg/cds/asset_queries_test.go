@@ -0,0 +1,234 @@
+package cds
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestAssetDataQuery(t *testing.T) {
+	basePath := "/api/" + apiVersion + "/tenants/" + tenantId + "/namespaces/" + namespaceId
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(basePath+"/assets/Asset1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"Id": "Asset1",
+			"Name": "Pump 1",
+			"Metadata": [{"Name": "Site", "SdsTypeCode": "String", "Value": "Houston"}, {"Id": "Rating", "SdsTypeCode": "Double", "Value": 1.5}],
+			"StreamReferences": [{"Id": "Flow", "Name": "Flow", "StreamId": "StreamId1"}]
+		}`))
+	})
+
+	mux.HandleFunc(basePath+"/streams/StreamId1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"TypeId": "StreamType1", "Id": "StreamId1", "Name": "StreamName1"}`))
+	})
+
+	mux.HandleFunc(basePath+"/types/StreamType1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"Id": "StreamType1",
+			"SdsTypeCode": 1,
+			"Properties": [
+				{"Id": "Timestamp", "IsKey": true, "SdsType": {"SdsTypeCode": 16}},
+				{"Id": "Value", "SdsType": {"SdsTypeCode": 14}}
+			]
+		}`))
+	})
+
+	mux.HandleFunc(basePath+"/streams/StreamId1/Data/Last", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"Timestamp": "2022-06-04T00:00:00Z", "Value": 2}`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	frames, err := AssetDataQuery(&client, namespaceId, "token", "Asset1", "", "", true, false)
+	if err != nil || len(frames) != 1 {
+		t.Fatalf("FAILED: expected 1 frame, got %v (%v)\n", frames, err)
+	}
+
+	frame := frames[0]
+	if frame.Name != "Pump 1.Flow" || frame.Rows() != 1 || frame.Fields[1].At(0).(float64) != 2 {
+		t.Errorf("FAILED: expected the last value of Pump 1.Flow, got %v\n", frame)
+	}
+
+	expected := data.Labels{"asset": "Pump 1", "streamReference": "Flow", "Site": "Houston", "Rating": "1.5"}
+	if frame.Fields[0].Labels != nil || !reflect.DeepEqual(frame.Fields[1].Labels, expected) {
+		t.Errorf("FAILED: expected value labels %v, got %v and %v\n", expected, frame.Fields[0].Labels, frame.Fields[1].Labels)
+	}
+}
+
+func TestAssetDataQueryFailedReference(t *testing.T) {
+	basePath := "/api/" + apiVersion + "/tenants/" + tenantId + "/namespaces/" + namespaceId
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(basePath+"/assets/Asset1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"Id": "Asset1",
+			"Name": "Pump 1",
+			"StreamReferences": [{"Id": "Flow", "Name": "Flow", "StreamId": "StreamId1"}, {"Id": "Pressure", "Name": "Pressure", "StreamId": "Missing"}]
+		}`))
+	})
+
+	mux.HandleFunc(basePath+"/streams/StreamId1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"TypeId": "StreamType1", "Id": "StreamId1", "Name": "StreamName1"}`))
+	})
+
+	mux.HandleFunc(basePath+"/types/StreamType1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"Id": "StreamType1",
+			"SdsTypeCode": 1,
+			"Properties": [
+				{"Id": "Timestamp", "IsKey": true, "SdsType": {"SdsTypeCode": 16}},
+				{"Id": "Value", "SdsType": {"SdsTypeCode": 14}}
+			]
+		}`))
+	})
+
+	mux.HandleFunc(basePath+"/streams/StreamId1/Data/Last", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"Timestamp": "2022-06-04T00:00:00Z", "Value": 2}`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// the readable stream reference is returned, with a notice naming the other one
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	frames, err := AssetDataQuery(&client, namespaceId, "token", "Asset1", "", "", true, false)
+	if err != nil || len(frames) != 1 || frames[0].Name != "Pump 1.Flow" {
+		t.Fatalf("FAILED: expected the Pump 1.Flow frame, got %v (%v)\n", frames, err)
+	}
+	notices := frames[0].Meta.Notices
+	if len(notices) != 1 || !strings.Contains(notices[0].Text, "Pressure") {
+		t.Errorf("FAILED: expected a notice naming the Pressure reference, got %v\n", notices)
+	}
+}
+
+func TestAssetStatusQuery(t *testing.T) {
+	basePath := "/api/" + apiVersion + "/tenants/" + tenantId + "/namespaces/" + namespaceId
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(basePath+"/assets", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[
+			{"Id": "Asset1", "Name": "Pump 1", "Metadata": [{"Name": "Site", "Value": "Houston"}]},
+			{"Id": "Asset2", "Name": "Pump 2", "Metadata": [{"Name": "Model", "Value": "P100"}]}
+		]`))
+	})
+
+	mux.HandleFunc(basePath+"/assets/Asset1/status", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"AssetId": "Asset1", "Value": {"DisplayName": "Running", "Value": 1, "Status": "Good"}, "Time": "2022-06-04T00:00:00Z"}`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	good, running, houston, model := "Good", "Running", "Houston", "P100"
+	lastUpdate := time.Date(2022, 6, 4, 0, 0, 0, 0, time.UTC)
+	expected := data.NewFrame("response",
+		data.NewField("Id", nil, []string{"Asset1", "Asset2"}),
+		data.NewField("Name", nil, []string{"Pump 1", "Pump 2"}),
+		data.NewField("Status", nil, []*string{&good, nil}),
+		data.NewField("StatusDisplayName", nil, []*string{&running, nil}),
+		data.NewField("LastUpdate", nil, []*time.Time{&lastUpdate, nil}),
+		data.NewField("Model", nil, []*string{nil, &model}),
+		data.NewField("Site", nil, []*string{&houston, nil}),
+	).SetMeta(&data.FrameMeta{Type: data.FrameTypeTable, TypeVersion: data.FrameTypeVersion{0, 1}})
+
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	resp, err := AssetStatusQuery(&client, namespaceId, "token", "Pump*", "", SearchOptions{})
+	if err != nil || !reflect.DeepEqual(resp, expected) {
+		t.Errorf("FAILED: expected %v, got %v (%v)\n", expected, resp, err)
+	}
+}
+
+func TestAssetStatusQueryErrors(t *testing.T) {
+	basePath := "/api/" + apiVersion + "/tenants/" + tenantId + "/namespaces/" + namespaceId
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(basePath+"/assets/Asset1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"Id": "Asset1", "Name": "Pump 1"}`))
+	})
+
+	mux.HandleFunc(basePath+"/assets/Asset1/status", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// failures other than a missing status are not shown as assets without a status
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	_, err := AssetStatusQuery(&client, namespaceId, "token", "", "Asset1", SearchOptions{})
+	if err == nil || !strings.Contains(err.Error(), "Asset1") {
+		t.Errorf("FAILED: expected an error naming Asset1, got %v\n", err)
+	}
+}
+
+func TestAssetStatusQueryLimit(t *testing.T) {
+	basePath := "/api/" + apiVersion + "/tenants/" + tenantId + "/namespaces/" + namespaceId
+	mux := http.NewServeMux()
+
+	// an endless search, paged by skip and count
+	searchRequests := 0
+	mux.HandleFunc(basePath+"/assets", func(w http.ResponseWriter, r *http.Request) {
+		searchRequests++
+		skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+		if count == 0 {
+			count = maxAssetStatuses + 1
+		}
+
+		results := make([]string, count)
+		for i := range results {
+			results[i] = fmt.Sprintf(`{"Id": "Asset%d", "Name": "Pump %d"}`, skip+i, skip+i)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("[" + strings.Join(results, ",") + "]"))
+	})
+
+	var statusRequests atomic.Int32
+	mux.HandleFunc(basePath+"/assets/", func(w http.ResponseWriter, r *http.Request) {
+		statusRequests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// the search stops once more assets are found than are shown
+	for _, options := range []SearchOptions{{}, {AllPages: true}} {
+		searchRequests = 0
+		statusRequests.Store(0)
+
+		client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+		resp, err := AssetStatusQuery(&client, namespaceId, "token", "", "", options)
+		if err != nil || resp.Rows() != maxAssetStatuses || statusRequests.Load() != maxAssetStatuses {
+			t.Fatalf("FAILED: expected %d assets, got %v after %d requests (%v)\n", maxAssetStatuses, resp, statusRequests.Load(), err)
+		}
+		if len(resp.Meta.Notices) != 1 {
+			t.Errorf("FAILED: expected a truncation notice, got %v\n", resp.Meta.Notices)
+		}
+		if searchRequests > 2 {
+			t.Errorf("FAILED: expected at most 2 search requests, got %d\n", searchRequests)
+		}
+	}
+}
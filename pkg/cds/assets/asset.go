@@ -0,0 +1,10 @@
+package assets
+
+type Asset struct {
+	Id               string            `json:"Id"`
+	Name             string            `json:"Name"`
+	Description      string            `json:"Description"`
+	AssetTypeId      string            `json:"AssetTypeId"`
+	Metadata         []MetadataItem    `json:"Metadata"`
+	StreamReferences []StreamReference `json:"StreamReferences"`
+}
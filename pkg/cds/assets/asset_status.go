@@ -0,0 +1,15 @@
+package assets
+
+// Status of an asset, as configured by its status mapping.
+type AssetStatus struct {
+	AssetId string           `json:"AssetId"`
+	Name    string           `json:"Name"`
+	Value   AssetStatusValue `json:"Value"`
+	Time    string           `json:"Time"`
+}
+
+type AssetStatusValue struct {
+	DisplayName string      `json:"DisplayName"`
+	Value       interface{} `json:"Value"`
+	Status      string      `json:"Status"`
+}
@@ -0,0 +1,23 @@
+package assets
+
+import (
+	"fmt"
+)
+
+type MetadataItem struct {
+	Id          string      `json:"Id"`
+	Name        string      `json:"Name"`
+	Description string      `json:"Description"`
+	SdsTypeCode string      `json:"SdsTypeCode"`
+	Value       interface{} `json:"Value"`
+	Uom         string      `json:"Uom"`
+}
+
+// Returns the value of the metadata item as text.
+func (item MetadataItem) String() string {
+	if item.Value == nil {
+		return ""
+	}
+
+	return fmt.Sprint(item.Value)
+}
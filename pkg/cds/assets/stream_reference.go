@@ -0,0 +1,8 @@
+package assets
+
+type StreamReference struct {
+	Id          string `json:"Id"`
+	Name        string `json:"Name"`
+	Description string `json:"Description"`
+	StreamId    string `json:"StreamId"`
+}
@@ -0,0 +1,151 @@
+package cds
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/aveva/connect-data-services/pkg/cds/sds"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Lists the types of a namespace, with one row per property of each type.
+func TypesQuery(d *CdsClient, namespaceId string, token string, query string, options SearchOptions) (*data.Frame, error) {
+	basePath := d.resource + "/api/" + d.apiVersion + "/tenants/" + url.QueryEscape(d.tenantId) + "/namespaces/" + url.QueryEscape(namespaceId)
+	path := (basePath + "/types?query=" + url.QueryEscape(query))
+
+	types, truncated, err := searchPages[sds.SdsType](d, token, path, nil, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return createTypePropertiesFrame(types, truncated), nil
+}
+
+// Reads the type of a stream in a namespace, with one row per property.
+func StreamTypeQuery(d *CdsClient, namespaceId string, token string, id string) (*data.Frame, error) {
+	basePath := d.resource + "/api/" + d.apiVersion + "/tenants/" + url.QueryEscape(d.tenantId) + "/namespaces/" + url.QueryEscape(namespaceId)
+	path := (basePath + "/streams/" + url.QueryEscape(id) + "/Type")
+
+	var sdsType sds.SdsType
+	err := cachedSdsRequest(d, token, path, nil, &sdsType)
+	if err != nil {
+		return nil, err
+	}
+
+	return createTypePropertiesFrame([]sds.SdsType{sdsType}, false), nil
+}
+
+// Reads the resolved type of a community stream, with one row per property.
+func CommunityStreamTypeQuery(d *CdsClient, communityId string, token string, self string) (*data.Frame, error) {
+	ref, err := parseCommunityStreamRef(d, self)
+	if err != nil {
+		return nil, err
+	}
+
+	communityHeader := map[string]string{
+		"Community-Id": url.QueryEscape(communityId),
+	}
+
+	var sdsResolvedStream sds.SdsResolvedStream
+	err = cachedSdsRequest(d, token, ref.path(d)+"/resolved", communityHeader, &sdsResolvedStream)
+	if err != nil {
+		return nil, err
+	}
+
+	return createTypePropertiesFrame([]sds.SdsType{sdsResolvedStream.SdsType}, false), nil
+}
+
+// Lists the stream views of a namespace.
+func StreamViewsQuery(d *CdsClient, namespaceId string, token string, query string, options SearchOptions) (*data.Frame, error) {
+	basePath := d.resource + "/api/" + d.apiVersion + "/tenants/" + url.QueryEscape(d.tenantId) + "/namespaces/" + url.QueryEscape(namespaceId)
+	path := (basePath + "/streamviews?query=" + url.QueryEscape(query))
+
+	streamViews, truncated, err := searchPages[sds.SdsStreamView](d, token, path, nil, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// create property lists from stream views list
+	ids := make([]string, len(streamViews))
+	names := make([]string, len(streamViews))
+	descriptions := make([]string, len(streamViews))
+	sourceTypeIds := make([]string, len(streamViews))
+	targetTypeIds := make([]string, len(streamViews))
+	mappings := make([]string, len(streamViews))
+	for i, streamView := range streamViews {
+		ids[i] = streamView.Id
+		names[i] = streamView.Name
+		descriptions[i] = streamView.Description
+		sourceTypeIds[i] = streamView.SourceTypeId
+		targetTypeIds[i] = streamView.TargetTypeId
+
+		properties := make([]string, len(streamView.Properties))
+		for j, property := range streamView.Properties {
+			properties[j] = property.SourceId + " > " + property.TargetId
+		}
+		mappings[i] = strings.Join(properties, ", ")
+	}
+
+	frame := data.NewFrame("response",
+		data.NewField("Id", nil, ids),
+		data.NewField("Name", nil, names),
+		data.NewField("Description", nil, descriptions),
+		data.NewField("SourceTypeId", nil, sourceTypeIds),
+		data.NewField("TargetTypeId", nil, targetTypeIds),
+		data.NewField("Properties", nil, mappings),
+	)
+	setCatalogMeta(frame, truncated, len(ids))
+
+	return frame, nil
+}
+
+// Creates a table of the properties of types, with the type of each property. Nested
+// properties are listed with their path, such as Position.X.
+func createTypePropertiesFrame(types []sds.SdsType, truncated bool) *data.Frame {
+	var typeIds, typeNames, propertyIds, propertyNames, typeCodes, uoms, descriptions []string
+	var isKeys []bool
+
+	var addProperties func(sdsType sds.SdsType, properties []sds.SdsTypeProperty, prefix string, depth int)
+	addProperties = func(sdsType sds.SdsType, properties []sds.SdsTypeProperty, prefix string, depth int) {
+		for _, property := range properties {
+			typeIds = append(typeIds, sdsType.Id)
+			typeNames = append(typeNames, sdsType.Name)
+			propertyIds = append(propertyIds, prefix+property.Id)
+			propertyNames = append(propertyNames, property.Name)
+			typeCodes = append(typeCodes, string(property.SdsType.SdsTypeCode))
+			isKeys = append(isKeys, property.IsKey && depth == 0)
+			uoms = append(uoms, property.Uom)
+			descriptions = append(descriptions, property.Description)
+
+			if depth < maxNestingDepth {
+				addProperties(sdsType, property.SdsType.Properties, prefix+property.Id+".", depth+1)
+			}
+		}
+	}
+
+	for _, sdsType := range types {
+		addProperties(sdsType, sdsType.Properties, "", 0)
+	}
+
+	frame := data.NewFrame("response",
+		data.NewField("TypeId", nil, typeIds),
+		data.NewField("TypeName", nil, typeNames),
+		data.NewField("PropertyId", nil, propertyIds),
+		data.NewField("PropertyName", nil, propertyNames),
+		data.NewField("SdsTypeCode", nil, typeCodes),
+		data.NewField("IsKey", nil, isKeys),
+		data.NewField("Uom", nil, uoms),
+		data.NewField("Description", nil, descriptions),
+	)
+	setCatalogMeta(frame, truncated, len(types))
+
+	return frame
+}
+
+// Marks a catalog frame as a table, with a notice when the results were truncated.
+func setCatalogMeta(frame *data.Frame, truncated bool, results int) {
+	frame.Meta = &data.FrameMeta{Type: data.FrameTypeTable, TypeVersion: data.FrameTypeVersion{0, 1}}
+	if truncated {
+		frame.Meta.Notices = []data.Notice{truncatedNotice(results)}
+	}
+}
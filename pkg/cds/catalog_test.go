@@ -0,0 +1,96 @@
+package cds
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestTypesQuery(t *testing.T) {
+	basePath := "/api/" + apiVersion + "/tenants/" + tenantId + "/namespaces/" + namespaceId
+	mux := http.NewServeMux()
+
+	sdsType := `{
+		"Id": "StreamType1",
+		"Name": "Pump",
+		"SdsTypeCode": 1,
+		"Properties": [
+			{"Id": "Timestamp", "IsKey": true, "SdsType": {"SdsTypeCode": 16}},
+			{"Id": "Temp", "Name": "Temperature", "Uom": "degree Celsius", "Description": "Inlet", "SdsType": {"SdsTypeCode": 14}},
+			{"Id": "Position", "SdsType": {"SdsTypeCode": 1, "Properties": [{"Id": "X", "SdsType": {"SdsTypeCode": 14}}]}}
+		]
+	}`
+
+	mux.HandleFunc(basePath+"/types", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("[" + sdsType + "]"))
+	})
+
+	mux.HandleFunc(basePath+"/streams/StreamId1/Type", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(sdsType))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	expected := data.NewFrame("response",
+		data.NewField("TypeId", nil, []string{"StreamType1", "StreamType1", "StreamType1", "StreamType1"}),
+		data.NewField("TypeName", nil, []string{"Pump", "Pump", "Pump", "Pump"}),
+		data.NewField("PropertyId", nil, []string{"Timestamp", "Temp", "Position", "Position.X"}),
+		data.NewField("PropertyName", nil, []string{"", "Temperature", "", ""}),
+		data.NewField("SdsTypeCode", nil, []string{"DateTime", "Double", "Object", "Double"}),
+		data.NewField("IsKey", nil, []bool{true, false, false, false}),
+		data.NewField("Uom", nil, []string{"", "degree Celsius", "", ""}),
+		data.NewField("Description", nil, []string{"", "Inlet", "", ""}),
+	).SetMeta(&data.FrameMeta{Type: data.FrameTypeTable, TypeVersion: data.FrameTypeVersion{0, 1}})
+
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	resp, err := TypesQuery(&client, namespaceId, "token", "", SearchOptions{})
+	if err != nil || !reflect.DeepEqual(resp, expected) {
+		t.Errorf("FAILED: expected %v, got %v (%v)\n", expected, resp, err)
+	}
+
+	resp, err = StreamTypeQuery(&client, namespaceId, "token", "StreamId1")
+	if err != nil || !reflect.DeepEqual(resp, expected) {
+		t.Errorf("FAILED: expected %v, got %v (%v)\n", expected, resp, err)
+	}
+}
+
+func TestStreamViewsQuery(t *testing.T) {
+	basePath := "/api/" + apiVersion + "/tenants/" + tenantId + "/namespaces/" + namespaceId
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != basePath+"/streamviews" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{
+			"Id": "View1",
+			"Name": "Celsius",
+			"SourceTypeId": "StreamType1",
+			"TargetTypeId": "StreamType2",
+			"Properties": [{"SourceId": "Temp", "TargetId": "Temperature"}, {"SourceId": "Timestamp", "TargetId": "Time"}]
+		}]`))
+	}))
+	defer server.Close()
+
+	expected := data.NewFrame("response",
+		data.NewField("Id", nil, []string{"View1"}),
+		data.NewField("Name", nil, []string{"Celsius"}),
+		data.NewField("Description", nil, []string{""}),
+		data.NewField("SourceTypeId", nil, []string{"StreamType1"}),
+		data.NewField("TargetTypeId", nil, []string{"StreamType2"}),
+		data.NewField("Properties", nil, []string{"Temp > Temperature, Timestamp > Time"}),
+	).SetMeta(&data.FrameMeta{Type: data.FrameTypeTable, TypeVersion: data.FrameTypeVersion{0, 1}})
+
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	resp, err := StreamViewsQuery(&client, namespaceId, "token", "", SearchOptions{})
+	if err != nil || !reflect.DeepEqual(resp, expected) {
+		t.Errorf("FAILED: expected %v, got %v (%v)\n", expected, resp, err)
+	}
+}
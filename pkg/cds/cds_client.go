@@ -1,11 +1,15 @@
 package cds
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,6 +28,9 @@ type CdsClient struct {
 	token           string
 	tokenExpiration int64
 	client          *http.Client
+	metadataCache   *metadataCache
+	tokenOptions    TokenOptions
+	eds             bool
 }
 
 func NewCdsClient(resource string, apiVersion string, tenantId string, clientId string, clientSecret string) CdsClient {
@@ -37,95 +44,185 @@ func NewCdsClient(resource string, apiVersion string, tenantId string, clientId
 	}
 }
 
+// Creates a client for Edge Data Store on a host and port. Edge Data Store is not
+// authenticated and stores all data in the default tenant.
+func NewEdsClient(host string, port string) (CdsClient, error) {
+	resource, err := edsResource(host, port)
+	if err != nil {
+		return CdsClient{}, err
+	}
+
+	return CdsClient{
+		resource:   resource,
+		apiVersion: "v1",
+		tenantId:   "default",
+		client:     &http.Client{},
+		eds:        true,
+	}, nil
+}
+
+// Builds the URL of Edge Data Store from a host, with an optional http or https scheme,
+// and a port. Hosts that include a port or a path are rejected, since the port is set
+// separately.
+func edsResource(host string, port string) (string, error) {
+	resource := strings.TrimSpace(host)
+	if !strings.Contains(resource, "://") {
+		resource = "http://" + resource
+	}
+
+	parsed, err := url.Parse(resource)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return "", fmt.Errorf("the Edge Data Store host %s is not a valid host name", host)
+	}
+	if parsed.Port() != "" {
+		return "", fmt.Errorf("the Edge Data Store host %s should not include a port, set the port separately", host)
+	}
+	if (parsed.Path != "" && parsed.Path != "/") || parsed.RawQuery != "" || parsed.Fragment != "" || parsed.User != nil {
+		return "", fmt.Errorf("the Edge Data Store host %s should only be a host name", host)
+	}
+
+	if number, err := strconv.Atoi(port); err != nil || number <= 0 || number > 65535 {
+		return "", fmt.Errorf("the Edge Data Store port %s is not a valid port", port)
+	}
+
+	return parsed.Scheme + "://" + net.JoinHostPort(parsed.Hostname(), port), nil
+}
+
 func GetClientToken(d *CdsClient) (string, error) {
+	if d.eds {
+		return "", nil
+	}
+
 	if (d.tokenExpiration - time.Now().Unix()) > (5 * 60) {
 		return ("Bearer " + d.token), nil
 	}
 
-	wellKnownEndpoint := d.resource + "/identity/.well-known/openid-configuration"
-	req, err := http.NewRequest("GET", wellKnownEndpoint, nil)
+	tokenEndpoint, err := discoverTokenEndpoint(d)
 	if err != nil {
-		log.DefaultLogger.Warn("Error forming request", err.Error())
+		return "", err
+	}
+
+	req, err := newTokenRequest(d, tokenEndpoint)
+	if err != nil {
+		log.DefaultLogger.Warn("Error forming token request", err.Error())
 		return "", err
 	}
 
 	resp, err := d.client.Do(req)
 	if err != nil {
-		log.DefaultLogger.Warn("Error requesting well known endpoints", err.Error())
+		log.DefaultLogger.Warn("Error requesting token", err.Error())
 		return "", err
 	}
+
 	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.DefaultLogger.Warn("Error reading response", err.Error())
+		log.DefaultLogger.Warn("Error requesting token", err.Error())
 		return "", err
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		err = fmt.Errorf("Status: " + resp.Status + "\nBody: " + string(body))
+		err = fmt.Errorf("Status: %s\nBody: %s", resp.Status, string(body))
 		log.DefaultLogger.Warn("Error making request", err)
 		return "", err
 	}
 
-	var openIdConfig map[string]interface{}
+	var tokenInformation map[string]interface{}
 
-	err = json.Unmarshal(body, &openIdConfig)
+	err = json.Unmarshal(body, &tokenInformation)
 	if err != nil {
 		log.DefaultLogger.Warn("Error parsing json", err.Error())
 		return "", err
 	}
 
-	tokenEndpoint := openIdConfig["token_endpoint"].(string)
+	d.token = tokenInformation["access_token"].(string)
+	d.tokenExpiration = int64(tokenInformation["expires_in"].(float64)) + time.Now().Unix()
 
-	resp, err = d.client.PostForm(tokenEndpoint,
-		url.Values{
-			"client_id":     {d.clientId},
-			"client_secret": {d.clientSecret},
-			"grant_type":    {"client_credentials"}})
+	return ("Bearer " + d.token), nil
+}
 
+// Reads the token endpoint from the OpenID configuration of the resource.
+func discoverTokenEndpoint(d *CdsClient) (string, error) {
+	wellKnownEndpoint := d.resource + "/identity/.well-known/openid-configuration"
+	req, err := http.NewRequest("GET", wellKnownEndpoint, nil)
 	if err != nil {
-		log.DefaultLogger.Warn("Error requesting token", err.Error())
+		log.DefaultLogger.Warn("Error forming request", err.Error())
 		return "", err
 	}
 
+	resp, err := d.client.Do(req)
+	if err != nil {
+		log.DefaultLogger.Warn("Error requesting well known endpoints", err.Error())
+		return "", err
+	}
 	defer resp.Body.Close()
 
-	body, err = ioutil.ReadAll(resp.Body)
+	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.DefaultLogger.Warn("Error requesting token", err.Error())
+		log.DefaultLogger.Warn("Error reading response", err.Error())
 		return "", err
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		err = fmt.Errorf("Status: " + resp.Status + "\nBody: " + string(body))
+		err = fmt.Errorf("Status: %s\nBody: %s", resp.Status, string(body))
 		log.DefaultLogger.Warn("Error making request", err)
 		return "", err
 	}
 
-	var tokenInformation map[string]interface{}
+	var openIdConfig map[string]interface{}
 
-	err = json.Unmarshal(body, &tokenInformation)
+	err = json.Unmarshal(body, &openIdConfig)
 	if err != nil {
 		log.DefaultLogger.Warn("Error parsing json", err.Error())
 		return "", err
 	}
 
-	d.token = tokenInformation["access_token"].(string)
-	d.tokenExpiration = int64(tokenInformation["expires_in"].(float64)) + time.Now().Unix()
+	tokenEndpoint, ok := openIdConfig["token_endpoint"].(string)
+	if !ok || tokenEndpoint == "" {
+		return "", fmt.Errorf("the OpenID configuration of %s has no token endpoint", d.resource)
+	}
 
-	return ("Bearer " + d.token), nil
+	return tokenEndpoint, nil
 }
 
 func SdsRequest(d *CdsClient, token string, path string, headers map[string]string) ([]byte, error) {
+	body, _, err := sdsRequest(d, token, path, headers)
+	return body, err
+}
+
+// Returned by SDS requests that receive an unsuccessful status, so that callers can tell
+// missing resources from other failures.
+type sdsStatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *sdsStatusError) Error() string {
+	return fmt.Sprintf("Status: %s\nBody: %s", e.Status, e.Body)
+}
+
+// Determines whether an error is a 404 Not Found response.
+func isNotFound(err error) bool {
+	var statusErr *sdsStatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// Makes a request to SDS and returns the response body and headers. A 304 Not Modified
+// response is not treated as an error so that conditional requests can be revalidated.
+func sdsRequest(d *CdsClient, token string, path string, headers map[string]string) ([]byte, *http.Response, error) {
 	log.DefaultLogger.Debug("Making query to", path)
 
 	// request data or collection items
 	req, err := http.NewRequest("GET", path, nil)
 	if err != nil {
 		log.DefaultLogger.Warn("Error forming request", err.Error())
-		return nil, err
+		return nil, nil, err
 	}
 
-	req.Header.Add("Authorization", token)
+	// requests to Edge Data Store are not authenticated
+	if token != "" {
+		req.Header.Add("Authorization", token)
+	}
 
 	// add optional headers
 	for k, v := range headers {
@@ -135,157 +232,196 @@ func SdsRequest(d *CdsClient, token string, path string, headers map[string]stri
 	resp, err := d.client.Do(req)
 	if err != nil {
 		log.DefaultLogger.Warn("Error making request", err.Error())
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		log.DefaultLogger.Warn("Error reading request body", err.Error())
-		return nil, err
+		return nil, nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return body, resp, nil
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		err = fmt.Errorf("Status: " + resp.Status + "\nBody: " + string(body))
+		err = &sdsStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(body)}
 		log.DefaultLogger.Warn("Error making request", err)
-		return nil, err
+		return nil, nil, err
 	}
 
-	return body, nil
+	return body, resp, nil
 }
 
-func StreamsQuery(d *CdsClient, namespaceId string, token string, query string) (*data.Frame, error) {
-	basePath := d.resource + "/api/" + d.apiVersion + "/tenants/" + url.QueryEscape(d.tenantId) + "/namespaces/" + url.QueryEscape(namespaceId)
-	path := (basePath + "/streams?query=" + url.QueryEscape(query))
+// Resolves a link returned by the resource, such as a next page or Self link, against the
+// resource of the client. Links to another scheme or host are rejected, so that the token
+// of the client is never sent anywhere else.
+func resolveResourceLink(d *CdsClient, link string) (*url.URL, error) {
+	resource, err := url.Parse(d.resource)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource %s: %w", d.resource, err)
+	}
 
-	body, err := SdsRequest(d, token, path, nil)
+	parsed, err := url.Parse(link)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid link %s: %w", link, err)
+	}
+
+	resolved := resource.ResolveReference(parsed)
+	if !strings.EqualFold(resolved.Scheme, resource.Scheme) || !strings.EqualFold(resolved.Host, resource.Host) {
+		return nil, fmt.Errorf("link %s is not hosted by %s", link, d.resource)
 	}
 
-	var streams []sds.SdsStream
+	return resolved, nil
+}
+
+func StreamsQuery(d *CdsClient, namespaceId string, token string, query string, options SearchOptions) (*data.Frame, error) {
+	basePath := d.resource + "/api/" + d.apiVersion + "/tenants/" + url.QueryEscape(d.tenantId) + "/namespaces/" + url.QueryEscape(namespaceId)
+	path := (basePath + "/streams?query=" + url.QueryEscape(query))
 
-	err = json.Unmarshal(body, &streams)
+	streams, truncated, err := searchPages[sds.SdsStream](d, token, path, nil, options)
 	if err != nil {
-		log.DefaultLogger.Warn("Error parsing json", err.Error())
-		log.DefaultLogger.Warn(fmt.Sprint(string(body)))
 		return nil, err
 	}
 
-	// create a dataframe
-	frame := data.NewFrame("response")
-
 	// create property lists from streams list
-	ids := make([]string, len(streams))
-	names := make([]string, len(streams))
+	columns := newStreamColumns(len(streams), options.IncludeMetadata)
 	for i := 0; i < len(streams); i++ {
-		ids[i] = streams[i].Id
-		names[i] = streams[i].Name
+		columns.add(streams[i].Id, streams[i].Name, streams[i].TypeId, streams[i].Description)
+		if options.IncludeMetadata {
+			columns.readMetadata(func() (map[string]string, []string) {
+				return streamMetadata(d, token, basePath+"/streams/"+url.QueryEscape(streams[i].Id), nil)
+			})
+		}
 	}
 
-	// add fields
-	frame.Fields = append(frame.Fields,
-		data.NewField("Id", nil, ids),
-		data.NewField("Name", nil, names),
-	)
-
-	return frame, nil
+	return columns.frame(truncated), nil
 }
 
-func CommunityStreamsQuery(d *CdsClient, communityId string, token string, query string) (*data.Frame, error) {
+func CommunityStreamsQuery(d *CdsClient, communityId string, token string, query string, options SearchOptions) (*data.Frame, error) {
 	basePath := d.resource + "/api/" + d.apiVersion + "/search/communities/" + url.QueryEscape(communityId)
 
 	path := (basePath + "/streams?query=" + url.QueryEscape(query))
 
-	body, err := SdsRequest(d, token, path, nil)
-	if err != nil {
-		return nil, err
+	// the search API has no tenant filter, so results of other tenants are filtered out
+	var keep func(community.StreamSearchResult) bool
+	if options.TenantId != "" {
+		keep = func(stream community.StreamSearchResult) bool {
+			return options.includesTenant(stream.TenantId)
+		}
 	}
 
-	var streams []community.StreamSearchResult
-
-	err = json.Unmarshal(body, &streams)
+	streams, truncated, err := filteredSearchPages(d, token, path, nil, options, keep)
 	if err != nil {
-		log.DefaultLogger.Warn("Error parsing json", err.Error())
-		log.DefaultLogger.Warn(fmt.Sprint(string(body)))
 		return nil, err
 	}
 
-	// create a dataframe
-	frame := data.NewFrame("response")
+	communityHeader := map[string]string{
+		"Community-Id": url.QueryEscape(communityId),
+	}
 
 	// create property lists from streams list
-	ids := make([]string, len(streams))
-	names := make([]string, len(streams))
+	columns := newCommunityStreamColumns(len(streams), options.IncludeMetadata)
 	for i := 0; i < len(streams); i++ {
-		// replace api version for compatibility with preview route
-		// this can be removed once community features are released
-		ids[i] = strings.Replace(streams[i].Self, "/v1/", "/"+d.apiVersion+"/", 1)
-		names[i] = streams[i].Name
+		// streams that are not hosted by the resource cannot be queried
+		ref, err := parseCommunityStreamRef(d, streams[i].Self)
+		if err != nil {
+			log.DefaultLogger.Warn("Skipping community stream", err.Error())
+			continue
+		}
+
+		self := ref.path(d)
+		columns.add(self, streams[i].Name, streams[i].TypeId, streams[i].Description)
+		columns.addCommunity(streams[i].TenantId, streams[i].TenantName, streams[i].NamespaceId)
+		if options.IncludeMetadata {
+			columns.readMetadata(func() (map[string]string, []string) {
+				return streamMetadata(d, token, self, communityHeader)
+			})
+		}
 	}
 
-	// add fields
-	frame.Fields = append(frame.Fields,
-		data.NewField("Id", nil, ids),
-		data.NewField("Name", nil, names),
-	)
+	return columns.frame(truncated), nil
+}
 
-	return frame, nil
+func StreamsDataQuery(d *CdsClient, namespaceId string, token string, id string, startIndex string, endIndex string, includeLabels bool) (*data.Frame, error) {
+	frame, _, err := streamsDataFrame(d, namespaceId, token, id, "/Data?startIndex="+url.QueryEscape(startIndex)+"&endIndex="+url.QueryEscape(endIndex),
+		func(sdsType sds.SdsType) error {
+			return validateIndexRange(sdsType, startIndex, endIndex)
+		}, includeLabels)
+	return frame, err
+}
+
+// Reads the last event of a stream in a namespace.
+func StreamsLastValueQuery(d *CdsClient, namespaceId string, token string, id string, includeLabels bool) (*data.Frame, error) {
+	frame, _, err := streamsDataFrame(d, namespaceId, token, id, "/Data/Last", nil, includeLabels)
+	return frame, err
 }
 
-func StreamsDataQuery(d *CdsClient, namespaceId string, token string, id string, startIndex string, endIndex string) (*data.Frame, error) {
+// Reads data of a stream in a namespace from a path relative to the stream, and returns
+// the frame with the type of the stream. The type is validated before data is requested.
+// The metadata and tags of the stream are added as labels when requested.
+func streamsDataFrame(d *CdsClient, namespaceId string, token string, id string, dataPath string, validate func(sds.SdsType) error, includeLabels bool) (*data.Frame, sds.SdsType, error) {
 	basePath := d.resource + "/api/" + d.apiVersion + "/tenants/" + url.QueryEscape(d.tenantId) + "/namespaces/" + url.QueryEscape(namespaceId)
 
 	// get type Id
 	path := (basePath + "/streams/" + url.QueryEscape(id))
-	body, err := SdsRequest(d, token, path, nil)
-	if err != nil {
-		return nil, err
-	}
-
 	var stream sds.SdsStream
-	err = json.Unmarshal(body, &stream)
+	err := cachedSdsRequest(d, token, path, nil, &stream)
 	if err != nil {
-		log.DefaultLogger.Warn("Error parsing json", err.Error())
-		log.DefaultLogger.Warn(fmt.Sprint(string(body)))
-		return nil, err
+		return nil, sds.SdsType{}, err
 	}
 
 	// get type info
 	path = (basePath + "/types/" + url.QueryEscape(stream.TypeId))
-	body, err = SdsRequest(d, token, path, nil)
-	if err != nil {
-		return nil, err
-	}
-
 	var sdsType sds.SdsType
-	err = json.Unmarshal(body, &sdsType)
+	err = cachedSdsRequest(d, token, path, nil, &sdsType)
 	if err != nil {
-		log.DefaultLogger.Warn("Error parsing json", err.Error())
-		log.DefaultLogger.Warn(fmt.Sprint(string(body)))
-		return nil, err
+		return nil, sds.SdsType{}, err
 	}
 
 	log.DefaultLogger.Info(fmt.Sprint(sdsType))
 
+	if validate != nil {
+		err = validate(sdsType)
+		if err != nil {
+			return nil, sds.SdsType{}, err
+		}
+	}
+
 	// get data
-	path = (basePath + "/streams/" + url.QueryEscape(id) + "/Data?startIndex=" + url.QueryEscape(startIndex) + "&endIndex=" + url.QueryEscape(endIndex))
-	body, err = SdsRequest(d, token, path, nil)
+	path = (basePath + "/streams/" + url.QueryEscape(id) + dataPath)
+	body, err := SdsRequest(d, token, path, nil)
 	if err != nil {
-		return nil, err
+		return nil, sds.SdsType{}, err
 	}
 
-	var sdsData []map[string]interface{}
-	err = json.Unmarshal(body, &sdsData)
+	frame, err := createDataFrameFromSdsData(stream.Name, applyPropertyOverrides(sdsType, stream.PropertyOverrides), eventsBody(body), path)
 	if err != nil {
-		log.DefaultLogger.Warn("Error parsing json", err.Error())
-		log.DefaultLogger.Warn(fmt.Sprint(string(body)))
-		return nil, err
+		return nil, sds.SdsType{}, err
 	}
 
-	return createDataFrameFromSdsData(stream.Name, sdsType, sdsData)
+	if includeLabels {
+		labelValueFields(frame, sdsType, streamLabels(d, token, basePath+"/streams/"+url.QueryEscape(id), nil))
+	}
+	return frame, sdsType, nil
 }
 
-func CommunityStreamsDataQuery(d *CdsClient, communityId string, token string, self string, startIndex string, endIndex string) (*data.Frame, error) {
+// Wraps a single event, as returned for the last value of a stream, in an array.
+func eventsBody(body []byte) []byte {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return append(append([]byte("["), trimmed...), ']')
+	}
+
+	return body
+}
+
+func CommunityStreamsDataQuery(d *CdsClient, communityId string, token string, self string, startIndex string, endIndex string, includeLabels bool) (*data.Frame, error) {
+	ref, err := parseCommunityStreamRef(d, self)
+	if err != nil {
+		return nil, err
+	}
+	self = ref.path(d)
 
 	// make a community header
 	communityHeader := map[string]string{
@@ -294,242 +430,229 @@ func CommunityStreamsDataQuery(d *CdsClient, communityId string, token string, s
 
 	// get stream
 	path := self
-	body, err := SdsRequest(d, token, path, communityHeader)
-	if err != nil {
-		return nil, err
-	}
-
 	var stream sds.SdsStream
-	err = json.Unmarshal(body, &stream)
+	err = cachedSdsRequest(d, token, path, communityHeader, &stream)
 	if err != nil {
-		log.DefaultLogger.Warn("Error parsing json", err.Error())
-		log.DefaultLogger.Warn(fmt.Sprint(string(body)))
 		return nil, err
 	}
 
 	// get resolved type info
 	path = (self + "/resolved")
-	body, err = SdsRequest(d, token, path, communityHeader)
+	var sdsResolvedStream sds.SdsResolvedStream
+	err = cachedSdsRequest(d, token, path, communityHeader, &sdsResolvedStream)
 	if err != nil {
 		return nil, err
 	}
 
-	var sdsResolvedStream sds.SdsResolvedStream
-	err = json.Unmarshal(body, &sdsResolvedStream)
+	err = validateIndexRange(sdsResolvedStream.SdsType, startIndex, endIndex)
 	if err != nil {
-		log.DefaultLogger.Warn("Error parsing json", err.Error())
-		log.DefaultLogger.Warn(fmt.Sprint(string(body)))
 		return nil, err
 	}
 
 	// get data
 	path = (self + "/Data?startIndex=" + url.QueryEscape(startIndex) + "&endIndex=" + url.QueryEscape(endIndex))
-	body, err = SdsRequest(d, token, path, communityHeader)
+	body, err := SdsRequest(d, token, path, communityHeader)
 	if err != nil {
 		return nil, err
 	}
 
-	var sdsData []map[string]interface{}
-	err = json.Unmarshal(body, &sdsData)
+	frame, err := createDataFrameFromSdsData(stream.Name, applyPropertyOverrides(sdsResolvedStream.SdsType, stream.PropertyOverrides), body, path)
 	if err != nil {
-		log.DefaultLogger.Warn("Error parsing json", err.Error())
-		log.DefaultLogger.Warn(fmt.Sprint(string(body)))
 		return nil, err
 	}
 
-	return createDataFrameFromSdsData(stream.Name, sdsResolvedStream.SdsType, sdsData)
+	if includeLabels {
+		labelValueFields(frame, sdsResolvedStream.SdsType, streamLabels(d, token, self, communityHeader))
+	}
+	labelValueFields(frame, sdsResolvedStream.SdsType, ref.labels())
+	return frame, nil
 }
 
-func createDataFrameFromSdsData(dataFrameName string, sdsType sds.SdsType, sdsData []map[string]interface{}) (*data.Frame, error) {
-	// create a dataframe
-	frame := data.NewFrame(dataFrameName)
-
-	// create columns in dataframe
-	for i := 0; i < len(sdsType.Properties); i++ {
-		typeCodeString := sdsType.Properties[i].SdsType.SdsTypeCode
-		frame.Fields = append(frame.Fields,
-			data.NewField(sdsType.Properties[i].Id, nil, createSdsValueList(typeCodeString)))
+// Reads the metadata and tags of a stream. Streams without metadata or tags are returned
+// without them, as are streams whose metadata or tags cannot be read.
+func streamMetadata(d *CdsClient, token string, streamPath string, headers map[string]string) (map[string]string, []string) {
+	var metadata map[string]string
+	err := cachedOptionalSdsRequest(d, token, streamPath+"/Metadata", headers, &metadata)
+	if err != nil {
+		log.DefaultLogger.Warn("Unable to read stream metadata", err.Error())
 	}
 
-	// add data to rows
-	for i := 0; i < len(sdsData); i++ {
-		row := make([]interface{}, len(sdsType.Properties))
-		for j := 0; j < len(sdsType.Properties); j++ {
-			row[j] = convertSdsValue(sdsType.Properties[j].SdsType.SdsTypeCode, sdsData[i][string(sdsType.Properties[j].Id)])
-		}
-		frame.AppendRow(row...)
+	var tags []string
+	err = cachedOptionalSdsRequest(d, token, streamPath+"/Tags", headers, &tags)
+	if err != nil {
+		log.DefaultLogger.Warn("Unable to read stream tags", err.Error())
 	}
 
-	return frame, nil
+	return metadata, tags
 }
 
-func createSdsValueList(sdsTypeCode sds.SdsTypeCode) interface{} {
-	switch t := sdsTypeCode; t {
-	case "DateTime":
-		return []time.Time{}
-	case "NullableDateTime":
-		return []*time.Time{}
-	case "Boolean":
-		return []bool{}
-	case "NullableBoolean":
-		return []*bool{}
-	case "Int16":
-		return []int16{}
-	case "NullableInt16":
-		return []*int16{}
-	case "UInt16":
-		return []uint16{}
-	case "NullableUInt16":
-		return []*uint16{}
-	case "Int32":
-		return []int32{}
-	case "NullableInt32":
-		return []*int32{}
-	case "UInt32":
-		return []uint32{}
-	case "NullableUInt32":
-		return []*uint32{}
-	case "Int64":
-		return []int64{}
-	case "NullableInt64":
-		return []*int64{}
-	case "UInt64":
-		return []uint64{}
-	case "NullableUInt64":
-		return []*uint32{}
-	case "Single":
-		return []float32{}
-	case "NullableSingle":
-		return []*float32{}
-	case "Double":
-		return []float64{}
-	case "NullableDouble":
-		return []*float64{}
-	default:
-		return []*string{}
+// Reads the metadata and tags of a stream as labels. Metadata is added as key value pairs
+// and the tags are joined into a tags label.
+func streamLabels(d *CdsClient, token string, streamPath string, headers map[string]string) data.Labels {
+	metadata, tags := streamMetadata(d, token, streamPath, headers)
+
+	labels := data.Labels{}
+	for key, value := range metadata {
+		labels[key] = value
+	}
+	if _, ok := labels["tags"]; !ok && len(tags) > 0 {
+		labels["tags"] = strings.Join(tags, ",")
 	}
+
+	return labels
 }
 
-func convertSdsValue(sdsTypeCode sds.SdsTypeCode, value interface{}) interface{} {
+// Adds labels to the fields of a frame that are not part of the index of the type.
+func labelValueFields(frame *data.Frame, sdsType sds.SdsType, labels data.Labels) {
+	if len(labels) == 0 {
+		return
+	}
 
-	switch t := sdsTypeCode; t {
-	case "DateTime":
-		if value == nil {
-			return value
-		}
-		timestamp, _ := time.Parse(time.RFC3339, value.(string))
-		return timestamp
-	case "NullableDateTime":
-		if value == nil {
-			return value
-		}
-		timestamp, _ := time.Parse(time.RFC3339, value.(string))
-		return &timestamp
-	case "Boolean":
-		if value == nil {
-			return false
-		}
-		return true
-	case "NullableBoolean":
-		if value == nil {
-			return value
-		}
-		valuePointer := true
-		return &valuePointer
-	case "Int16":
-		if value == nil {
-			return int16(0)
-		}
-		return int16(value.(float64))
-	case "NullableInt16":
-		if value == nil {
-			return value
-		}
-		valuePointer := int16(value.(float64))
-		return &valuePointer
-	case "UInt16":
-		if value == nil {
-			return uint16(0)
-		}
-		return uint16(value.(float64))
-	case "NullableUInt16":
-		if value == nil {
-			return value
-		}
-		valuePointer := uint16(value.(float64))
-		return &valuePointer
-	case "Int32":
-		if value == nil {
-			return int32(0)
-		}
-		return int32(value.(float64))
-	case "NullableInt32":
-		if value == nil {
-			return value
-		}
-		valuePointer := int32(value.(float64))
-		return &valuePointer
-	case "UInt32":
-		if value == nil {
-			return uint32(0)
-		}
-		return uint32(value.(float64))
-	case "NullableUInt32":
-		if value == nil {
-			return value
+	index := make(map[string]bool)
+	for _, property := range sdsType.Index() {
+		index[property.Id] = true
+	}
+
+	for _, field := range frame.Fields {
+		if index[field.Name] {
+			continue
 		}
-		valuePointer := uint32(value.(float64))
-		return &valuePointer
-	case "Int64":
-		if value == nil {
-			return int64(0)
+
+		if field.Labels == nil {
+			field.Labels = data.Labels{}
 		}
-		return int64(value.(float64))
-	case "NullableInt64":
-		if value == nil {
-			return value
+		for key, value := range labels {
+			field.Labels[key] = value
 		}
-		valuePointer := int64(value.(float64))
-		return &valuePointer
-	case "UInt64":
-		if value == nil {
-			return uint64(0)
+	}
+}
+
+// Returns a copy of a type with the units of measure overridden by a stream. Nested
+// properties are overridden by their path, such as Position.X.
+func applyPropertyOverrides(sdsType sds.SdsType, overrides []sds.SdsStreamPropertyOverride) sds.SdsType {
+	if len(overrides) == 0 {
+		return sdsType
+	}
+
+	uoms := make(map[string]string, len(overrides))
+	for _, override := range overrides {
+		if override.Uom != "" {
+			uoms[override.SdsTypePropertyId] = override.Uom
 		}
-		return uint64(value.(float64))
-	case "NullableUInt64":
-		if value == nil {
-			return value
+	}
+
+	return overridePropertyUoms(sdsType, "", uoms)
+}
+
+// Returns a copy of a type with the units of measure of its properties, and of the
+// properties of nested objects, replaced by the units of measure keyed by property path.
+func overridePropertyUoms(sdsType sds.SdsType, prefix string, uoms map[string]string) sds.SdsType {
+	properties := make([]sds.SdsTypeProperty, len(sdsType.Properties))
+	for i, property := range sdsType.Properties {
+		path := prefix + property.Id
+		if uom, ok := uoms[path]; ok {
+			property.Uom = uom
 		}
-		valuePointer := uint64(value.(float64))
-		return &valuePointer
-	case "Single":
-		if value == nil {
-			return float32(0)
+		if len(property.SdsType.Properties) > 0 {
+			property.SdsType = overridePropertyUoms(property.SdsType, path+".", uoms)
 		}
-		return float32(value.(float64))
-	case "NullableSingle":
-		if value == nil {
-			return value
+		properties[i] = property
+	}
+	sdsType.Properties = properties
+
+	return sdsType
+}
+
+// Checks that an index range can be used with a type. Timestamps, such as those from the
+// dashboard time range, are rejected for types whose primary index is not a DateTime or a
+// string, since SDS cannot interpret them.
+func validateIndexRange(sdsType sds.SdsType, startIndex string, endIndex string) error {
+	index := sdsType.Index()
+	if sdsType.IsTimeIndexed() || len(index) == 0 || index[0].SdsType.SdsTypeCode == "String" {
+		return nil
+	}
+
+	for _, value := range []string{startIndex, endIndex} {
+		if _, err := time.Parse(time.RFC3339, value); err == nil {
+			return fmt.Errorf("type %s is indexed by %s property %s, specify a start and end index instead of using the dashboard time range",
+				sdsType.Id, index[0].SdsType.SdsTypeCode, index[0].Id)
 		}
-		valuePointer := float32(value.(float64))
-		return &valuePointer
-	case "Double":
-		if value == nil {
-			return float64(0)
+	}
+
+	return nil
+}
+
+// Converts an SDS data response into a frame with one field per property of the SdsType.
+// The JSON is decoded as a stream, directly into typed columns, so that large responses
+// are not first decoded into a map per event. Nested types are flattened into fields
+// named with the property path, and arrays are expanded into one field per index.
+// The frame follows the Grafana data plane contract: the index fields come first, and
+// the frame type and the executed query are recorded in the frame metadata.
+func createDataFrameFromSdsData(dataFrameName string, sdsType sds.SdsType, body []byte, executedQuery string) (*data.Frame, error) {
+	capacity := estimateEventCount(sdsType, body)
+	columns := newObjectColumn(indexFirst(sdsType), capacity, 0)
+
+	err := decodeSdsEvents(body, columns)
+	if err != nil {
+		log.DefaultLogger.Warn("Error parsing json", err.Error())
+		return nil, err
+	}
+
+	// create a dataframe
+	frame := data.NewFrame(dataFrameName)
+	frame.Fields = columns.fields(fieldPath{})
+
+	// data that is not a wide time series is a table
+	frame.Meta = &data.FrameMeta{
+		Type:                data.FrameTypeTable,
+		TypeVersion:         data.FrameTypeVersion{0, 1},
+		ExecutedQueryString: executedQuery,
+	}
+	if isWideTimeSeries(sdsType, frame.Fields) {
+		frame.Meta.Type = data.FrameTypeTimeSeriesWide
+	}
+
+	// report values that could not be converted without failing the query
+	notices := columns.notices("")
+	if len(notices) > 0 {
+		for _, notice := range notices {
+			log.DefaultLogger.Warn("Error converting values", "frame", dataFrameName, "notice", notice.Text)
 		}
-		return value.(float64)
-	case "NullableDouble":
-		if value == nil {
-			return value
+		frame.Meta.Notices = notices
+	}
+
+	return frame, nil
+}
+
+// Determines whether the fields of a type form a wide time series: a single time index,
+// which has unique timestamps, followed by numeric or boolean values. Compound indexes
+// repeat timestamps, and text or JSON values cannot be plotted, so these are tables.
+func isWideTimeSeries(sdsType sds.SdsType, fields []*data.Field) bool {
+	return sdsType.IsTimeIndexed() && len(sdsType.Index()) == 1 && hasTimeSeriesValues(fields)
+}
+
+// Determines whether the fields following the time index of a frame are numeric or
+// boolean values that can be plotted.
+func hasTimeSeriesValues(fields []*data.Field) bool {
+	for _, field := range fields[1:] {
+		fieldType := field.Type()
+		if !fieldType.Numeric() && fieldType != data.FieldTypeBool && fieldType != data.FieldTypeNullableBool {
+			return false
 		}
-		valuePointer := value.(float64)
-		return &valuePointer
-	default:
-		log.DefaultLogger.Info("Default")
-		if value == nil {
-			return value
+	}
+
+	return true
+}
+
+// Returns the properties of a type with the key properties first, in index order, so that
+// the time field of a time series is the first field of the frame.
+func indexFirst(sdsType sds.SdsType) []sds.SdsTypeProperty {
+	properties := sdsType.Index()
+	for _, property := range sdsType.Properties {
+		if !property.IsKey {
+			properties = append(properties, property)
 		}
-		valuePointer := value.(string)
-		return &valuePointer
 	}
+
+	return properties
 }
@@ -1,13 +1,20 @@
 package cds
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/aveva/connect-data-services/pkg/cds/sds"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 )
 
@@ -59,6 +66,8 @@ func TestStreamsQuery(t *testing.T) {
 			response: data.NewFrame("response",
 				data.NewField("Id", nil, []string{"StreamId1", "StreamId2", "StreamId3"}),
 				data.NewField("Name", nil, []string{"StreamName1", "StreamName2", "StreamName3"}),
+				data.NewField("TypeId", nil, []string{"StreamType1", "StreamType2", "StreamType3"}),
+				data.NewField("Description", nil, []string{"", "", ""}),
 			),
 			expectedError: nil,
 		},
@@ -69,7 +78,7 @@ func TestStreamsQuery(t *testing.T) {
 			defer test.server.Close()
 
 			client := NewCdsClient(test.server.URL, apiVersion, tenantId, "", "")
-			resp, err := StreamsQuery(&client, namespaceId, "token", "")
+			resp, err := StreamsQuery(&client, namespaceId, "token", "", SearchOptions{})
 
 			if !reflect.DeepEqual(resp, test.response) {
 				t.Errorf("FAILED: expected %v, got %v\n", test.response, resp)
@@ -184,14 +193,19 @@ func TestStreamsDataQuery(t *testing.T) {
 		]`))
 	})
 
+	server := httptest.NewServer(mux)
 	tests := []Tests{
 		{
 			name:   "streams-data-query",
-			server: httptest.NewServer(mux),
+			server: server,
 			response: data.NewFrame("StreamName1",
 				data.NewField("Timestamp", nil, []time.Time{time.Date(2022, 6, 4, 0, 0, 0, 0, time.UTC), time.Date(2022, 6, 5, 0, 0, 0, 0, time.UTC)}),
 				data.NewField("Value", nil, []float32{float32(0), float32(1)}),
-			),
+			).SetMeta(&data.FrameMeta{
+				Type:                data.FrameTypeTimeSeriesWide,
+				TypeVersion:         data.FrameTypeVersion{0, 1},
+				ExecutedQueryString: server.URL + basePath + "/streams/StreamId1/Data?startIndex=&endIndex=",
+			}),
 			expectedError: nil,
 		},
 	}
@@ -201,7 +215,7 @@ func TestStreamsDataQuery(t *testing.T) {
 			defer test.server.Close()
 
 			client := NewCdsClient(test.server.URL, apiVersion, tenantId, "", "")
-			resp, err := StreamsDataQuery(&client, namespaceId, "token", "StreamId1", "", "")
+			resp, err := StreamsDataQuery(&client, namespaceId, "token", "StreamId1", "", "", false)
 
 			if !reflect.DeepEqual(resp, test.response) {
 				t.Errorf("FAILED: expected %v, got %v\n", test.response, resp)
@@ -213,51 +227,219 @@ func TestStreamsDataQuery(t *testing.T) {
 	}
 }
 
+func TestStreamsQueryPaging(t *testing.T) {
+	basePath := "/api/" + apiVersion + "/tenants/" + tenantId + "/namespaces/" + namespaceId
+	mux := http.NewServeMux()
+
+	// five streams, returned a page at a time
+	var pages []string
+	mux.HandleFunc(basePath+"/streams", func(w http.ResponseWriter, r *http.Request) {
+		pages = append(pages, r.URL.RawQuery)
+		skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+
+		var streams []string
+		for i := skip; i < 5 && i < skip+count; i++ {
+			streams = append(streams, fmt.Sprintf(`{"Id": "StreamId%d", "Name": "StreamName%d", "TypeId": "StreamType1"}`, i, i))
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("[" + strings.Join(streams, ",") + "]"))
+	})
+
+	mux.HandleFunc(basePath+"/streams/StreamId0/Metadata", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"site": "Houston"}`))
+	})
+
+	mux.HandleFunc(basePath+"/streams/StreamId0/Tags", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`["pump"]`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	resp, err := StreamsQuery(&client, namespaceId, "token", "Stream*", SearchOptions{Count: 2, OrderBy: "Name desc", AllPages: true})
+	if err != nil || resp.Rows() != 5 {
+		t.Errorf("FAILED: expected 5 streams, got %v (%v)\n", resp, err)
+	}
+
+	expectedPages := []string{
+		"query=Stream%2A&count=2&orderby=Name+desc",
+		"query=Stream%2A&count=2&orderby=Name+desc&skip=2",
+		"query=Stream%2A&count=2&orderby=Name+desc&skip=4",
+	}
+	if !reflect.DeepEqual(pages, expectedPages) {
+		t.Errorf("FAILED: expected pages %v, got %v\n", expectedPages, pages)
+	}
+
+	// a single page with the metadata and tags of each stream
+	resp, err = StreamsQuery(&client, namespaceId, "token", "", SearchOptions{Skip: 0, Count: 1, IncludeMetadata: true})
+	expected := data.NewFrame("response",
+		data.NewField("Id", nil, []string{"StreamId0"}),
+		data.NewField("Name", nil, []string{"StreamName0"}),
+		data.NewField("TypeId", nil, []string{"StreamType1"}),
+		data.NewField("Description", nil, []string{""}),
+		data.NewField("Tags", nil, []string{"pump"}),
+		data.NewField("Metadata", nil, []json.RawMessage{json.RawMessage(`{"site":"Houston"}`)}),
+	)
+	if err != nil || !reflect.DeepEqual(resp, expected) {
+		t.Errorf("FAILED: expected %v, got %v (%v)\n", expected, resp, err)
+	}
+}
+
+func TestStreamColumnsMetadataLimit(t *testing.T) {
+	reads := 0
+	read := func() (map[string]string, []string) {
+		reads++
+		return map[string]string{"site": "Houston"}, []string{"pump"}
+	}
+
+	columns := newStreamColumns(maxMetadataStreams+1, true)
+	for i := 0; i <= maxMetadataStreams; i++ {
+		columns.add(fmt.Sprintf("StreamId%d", i), "", "", "")
+		columns.readMetadata(read)
+	}
+
+	frame := columns.frame(false)
+	if reads != maxMetadataStreams || frame.Fields[4].At(maxMetadataStreams) != "" {
+		t.Errorf("FAILED: expected metadata of %d streams, got %d reads\n", maxMetadataStreams, reads)
+	}
+	if frame.Meta == nil || len(frame.Meta.Notices) != 1 {
+		t.Errorf("FAILED: expected a notice for the streams without metadata, got %v\n", frame.Meta)
+	}
+}
+
+func TestNewEdsClient(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		port     string
+		expected string
+	}{
+		{name: "host", host: "localhost", port: "5590", expected: "http://localhost:5590"},
+		{name: "scheme", host: "https://eds.example/", port: "5590", expected: "https://eds.example:5590"},
+		{name: "ipv6", host: "::1", port: "5590", expected: ""},
+		{name: "bracketed-ipv6", host: "[::1]", port: "5590", expected: "http://[::1]:5590"},
+		{name: "host-port", host: "localhost:5590", port: "5590", expected: ""},
+		{name: "url-port", host: "http://host:5590/", port: "5590", expected: ""},
+		{name: "path", host: "http://host/eds", port: "5590", expected: ""},
+		{name: "invalid-port", host: "localhost", port: "port", expected: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := NewEdsClient(test.host, test.port)
+			if test.expected == "" {
+				if err == nil {
+					t.Errorf("FAILED: expected %s:%s to be rejected, got %v\n", test.host, test.port, client.resource)
+				}
+				return
+			}
+			if err != nil || client.resource != test.expected {
+				t.Errorf("FAILED: expected %v, got %v (%v)\n", test.expected, client.resource, err)
+			}
+		})
+	}
+}
+
+func TestEdsStreamsQuery(t *testing.T) {
+	mux := http.NewServeMux()
+
+	// Edge Data Store requests are not authenticated and use the default tenant
+	var authorization []string
+	mux.HandleFunc("/api/v1/tenants/default/namespaces/default/streams", func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Values("Authorization")...)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"Id": "StreamId1", "Name": "StreamName1", "TypeId": "StreamType1"}]`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	serverUrl, _ := url.Parse(server.URL)
+	client, err := NewEdsClient(serverUrl.Hostname(), serverUrl.Port())
+	if err != nil {
+		t.Fatalf("FAILED: unexpected error %v\n", err)
+	}
+
+	token, err := GetClientToken(&client)
+	if err != nil || token != "" {
+		t.Fatalf("FAILED: expected no token, got %v (%v)\n", token, err)
+	}
+
+	resp, err := StreamsQuery(&client, "default", token, "", SearchOptions{})
+	expected := data.NewFrame("response",
+		data.NewField("Id", nil, []string{"StreamId1"}),
+		data.NewField("Name", nil, []string{"StreamName1"}),
+		data.NewField("TypeId", nil, []string{"StreamType1"}),
+		data.NewField("Description", nil, []string{""}),
+	)
+	if err != nil || !reflect.DeepEqual(resp, expected) {
+		t.Errorf("FAILED: expected %v, got %v (%v)\n", expected, resp, err)
+	}
+	if len(authorization) != 0 {
+		t.Errorf("FAILED: expected no authorization header, got %v\n", authorization)
+	}
+}
+
 func TestCommunityStreamsQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := "http://" + r.Host
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[
+			{
+				"Name": "StreamName1",
+				"Id": "StreamId1",
+				"TypeId": "StreamType1",
+				"Description": "",
+				"Self": "` + host + `/api/v1-preview/tenants/tenantId1/namespaces/namespaceId1/streams/StreamId1",
+				"TenantId": "tenantId1",
+				"TenantName": "tenantName1",
+				"NamespaceId": "namespaceId1",
+				"CommunityId": "communityId1"
+			},
+			{
+				"Name": "StreamName2",
+				"Id": "StreamId2",
+				"TypeId": "StreamType2",
+				"Description": "",
+				"Self": "` + host + `/api/v1/tenants/tenantId2/namespaces/namespaceId2/streams/Stream%20Id2",
+				"TenantId": "tenantId2",
+				"TenantName": "tenantName2",
+				"NamespaceId": "namespaceId2",
+				"CommunityId": "communityId2"
+			},
+			{
+				"Name": "StreamName3",
+				"Id": "StreamId3",
+				"TypeId": "StreamType3",
+				"Description": "",
+				"Self": "http://host/api/v1/tenants/tenantId3/namespaces/namespaceId3/streams/StreamId3",
+				"TenantId": "tenantId3",
+				"TenantName": "tenantName3",
+				"NamespaceId": "namespaceId3",
+				"CommunityId": "communityId3"
+			}
+		]`))
+	}))
 	tests := []Tests{
 		{
-			name: "community-streams-query",
-			server: httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte(`[
-					{
-						"Name": "StreamName1",
-						"Id": "StreamId1",
-						"TypeId": "StreamType1",
-						"Description": "",
-						"Self": "http://host/StreamId1",
-						"TenantId": "tenantId1",
-						"TenantName": "tenantName1",
-						"NamespaceId": "namespaceId1",
-						"CommunityId": "communityId1"
-					},
-					{
-						"Name": "StreamName2",
-						"Id": "StreamId2",
-						"TypeId": "StreamType2",
-						"Description": "",
-						"Self": "http://host/StreamId2",
-						"TenantId": "tenantId2",
-						"TenantName": "tenantName2",
-						"NamespaceId": "namespaceId2",
-						"CommunityId": "communityId2"
-					},
-					{
-						"Name": "StreamName3",
-						"Id": "StreamId3",
-						"TypeId": "StreamType3",
-						"Description": "",
-						"Self": "http://host/StreamId3",
-						"TenantId": "tenantId3",
-						"TenantName": "tenantName3",
-						"NamespaceId": "namespaceId3",
-						"CommunityId": "communityId3"
-					}
-				]`))
-			})),
+			name:   "community-streams-query",
+			server: server,
 			response: data.NewFrame("response",
-				data.NewField("Id", nil, []string{"http://host/StreamId1", "http://host/StreamId2", "http://host/StreamId3"}),
-				data.NewField("Name", nil, []string{"StreamName1", "StreamName2", "StreamName3"}),
+				data.NewField("Id", nil, []string{
+					server.URL + "/api/" + apiVersion + "/tenants/tenantId1/namespaces/namespaceId1/streams/StreamId1",
+					server.URL + "/api/" + apiVersion + "/tenants/tenantId2/namespaces/namespaceId2/streams/Stream%20Id2",
+				}),
+				data.NewField("Name", nil, []string{"StreamName1", "StreamName2"}),
+				data.NewField("TypeId", nil, []string{"StreamType1", "StreamType2"}),
+				data.NewField("Description", nil, []string{"", ""}),
+				data.NewField("TenantId", nil, []string{"tenantId1", "tenantId2"}),
+				data.NewField("TenantName", nil, []string{"tenantName1", "tenantName2"}),
+				data.NewField("NamespaceId", nil, []string{"namespaceId1", "namespaceId2"}),
 			),
 			expectedError: nil,
 		},
@@ -268,7 +450,7 @@ func TestCommunityStreamsQuery(t *testing.T) {
 			defer test.server.Close()
 
 			client := NewCdsClient(test.server.URL, apiVersion, tenantId, "", "")
-			resp, err := CommunityStreamsQuery(&client, namespaceId, "token", "")
+			resp, err := CommunityStreamsQuery(&client, namespaceId, "token", "", SearchOptions{})
 
 			if !reflect.DeepEqual(resp, test.response) {
 				t.Errorf("FAILED: expected %v, got %v\n", test.response, resp)
@@ -280,6 +462,31 @@ func TestCommunityStreamsQuery(t *testing.T) {
 	}
 }
 
+func TestCommunityStreamsQueryOtherTenant(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"Id": "StreamId1", "Self": "http://` + r.Host + `/api/v1/tenants/tenantId1/namespaces/namespaceId1/streams/StreamId1", "TenantId": "tenantId1"}]`))
+	}))
+	defer server.Close()
+
+	// the community columns are returned even when no stream of the tenant is found
+	expected := data.NewFrame("response",
+		data.NewField("Id", nil, []string{}),
+		data.NewField("Name", nil, []string{}),
+		data.NewField("TypeId", nil, []string{}),
+		data.NewField("Description", nil, []string{}),
+		data.NewField("TenantId", nil, []string{}),
+		data.NewField("TenantName", nil, []string{}),
+		data.NewField("NamespaceId", nil, []string{}),
+	)
+
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	resp, err := CommunityStreamsQuery(&client, communityId, "token", "", SearchOptions{TenantId: "tenantId2"})
+	if err != nil || !reflect.DeepEqual(resp, expected) {
+		t.Errorf("FAILED: expected %v, got %v (%v)\n", expected, resp, err)
+	}
+}
+
 func TestCommunityStreamsDataQuery(t *testing.T) {
 	basePath := "/api/" + apiVersion + "/tenants/" + tenantId + "/namespaces/" + namespaceId
 	mux := http.NewServeMux()
@@ -384,14 +591,19 @@ func TestCommunityStreamsDataQuery(t *testing.T) {
 		]`))
 	})
 
+	server := httptest.NewServer(mux)
 	tests := []Tests{
 		{
 			name:   "community-streams-data-query",
-			server: httptest.NewServer(mux),
+			server: server,
 			response: data.NewFrame("StreamName1",
 				data.NewField("Timestamp", nil, []time.Time{time.Date(2022, 6, 4, 0, 0, 0, 0, time.UTC), time.Date(2022, 6, 5, 0, 0, 0, 0, time.UTC)}),
-				data.NewField("Value", nil, []float32{float32(0), float32(1)}),
-			),
+				data.NewField("Value", data.Labels{"tenantId": tenantId, "namespaceId": namespaceId}, []float32{float32(0), float32(1)}),
+			).SetMeta(&data.FrameMeta{
+				Type:                data.FrameTypeTimeSeriesWide,
+				TypeVersion:         data.FrameTypeVersion{0, 1},
+				ExecutedQueryString: server.URL + basePath + "/streams/StreamId1/Data?startIndex=&endIndex=",
+			}),
 			expectedError: nil,
 		},
 	}
@@ -401,7 +613,7 @@ func TestCommunityStreamsDataQuery(t *testing.T) {
 			defer test.server.Close()
 
 			client := NewCdsClient(test.server.URL, apiVersion, tenantId, "", "")
-			resp, err := CommunityStreamsDataQuery(&client, communityId, "token", test.server.URL+basePath+"/streams/StreamId1", "", "")
+			resp, err := CommunityStreamsDataQuery(&client, communityId, "token", test.server.URL+basePath+"/streams/StreamId1", "", "", false)
 
 			if !reflect.DeepEqual(resp, test.response) {
 				t.Errorf("FAILED: expected %v, got %v\n", test.response, resp)
@@ -412,3 +624,393 @@ func TestCommunityStreamsDataQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestStreamsDataQueryMetadataCache(t *testing.T) {
+	basePath := "/api/" + apiVersion + "/tenants/" + tenantId + "/namespaces/" + namespaceId
+	mux := http.NewServeMux()
+	requests := map[string]int{}
+	revalidations := 0
+
+	metadata := map[string]string{
+		basePath + "/streams/StreamId1": `{"TypeId": "StreamType1", "Id": "StreamId1", "Name": "StreamName1"}`,
+		basePath + "/types/StreamType1": `{
+			"Id": "StreamType1",
+			"SdsTypeCode": 1,
+			"Properties": [
+				{"Id": "Timestamp", "IsKey": true, "SdsType": {"SdsTypeCode": 16}},
+				{"Id": "Value", "SdsType": {"SdsTypeCode": 14}}
+			]
+		}`,
+	}
+
+	for path, body := range metadata {
+		path, body := path, body
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			requests[path]++
+			if r.Header.Get("If-None-Match") == `"etag1"` {
+				revalidations++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"etag1"`)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(body))
+		})
+	}
+
+	mux.HandleFunc(basePath+"/streams/StreamId1/Data", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"Timestamp": "2022-06-04T00:00:00Z", "Value": 1}]`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	expected := data.NewFrame("StreamName1",
+		data.NewField("Timestamp", nil, []time.Time{time.Date(2022, 6, 4, 0, 0, 0, 0, time.UTC)}),
+		data.NewField("Value", nil, []float64{1}),
+	).SetMeta(&data.FrameMeta{
+		Type:                data.FrameTypeTimeSeriesWide,
+		TypeVersion:         data.FrameTypeVersion{0, 1},
+		ExecutedQueryString: server.URL + basePath + "/streams/StreamId1/Data?startIndex=&endIndex=",
+	})
+
+	// fresh entries are served without a request
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	client.metadataCache = newMetadataCache(time.Minute)
+	for i := 0; i < 3; i++ {
+		resp, err := StreamsDataQuery(&client, namespaceId, "token", "StreamId1", "", "", false)
+		if err != nil || !reflect.DeepEqual(resp, expected) {
+			t.Errorf("FAILED: expected %v, got %v (%v)\n", expected, resp, err)
+		}
+	}
+	for path := range metadata {
+		if requests[path] != 1 {
+			t.Errorf("FAILED: expected 1 request to %s, got %d\n", path, requests[path])
+		}
+	}
+
+	// expired entries are revalidated with their ETag
+	for _, entry := range client.metadataCache.entries {
+		entry.expires = time.Now().Add(-time.Second)
+	}
+	resp, err := StreamsDataQuery(&client, namespaceId, "token", "StreamId1", "", "", false)
+	if err != nil || !reflect.DeepEqual(resp, expected) {
+		t.Errorf("FAILED: expected %v, got %v (%v)\n", expected, resp, err)
+	}
+	if revalidations != 2 {
+		t.Errorf("FAILED: expected 2 revalidations, got %d\n", revalidations)
+	}
+}
+
+func TestStreamsIndexQuery(t *testing.T) {
+	basePath := "/api/" + apiVersion + "/tenants/" + tenantId + "/namespaces/" + namespaceId
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(basePath+"/streams/StreamId1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"TypeId": "StreamType1", "Id": "StreamId1", "Name": "StreamName1"}`))
+	})
+
+	// compound index of Batch then Step, listed out of order
+	mux.HandleFunc(basePath+"/types/StreamType1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"Id": "StreamType1",
+			"SdsTypeCode": 1,
+			"Properties": [
+				{"Id": "Step", "IsKey": true, "Order": 1, "SdsType": {"SdsTypeCode": 9}},
+				{"Id": "Batch", "IsKey": true, "Order": 0, "SdsType": {"SdsTypeCode": 9}},
+				{"Id": "Value", "SdsType": {"SdsTypeCode": 14}}
+			]
+		}`))
+	})
+
+	var startIndex, endIndex string
+	mux.HandleFunc(basePath+"/streams/StreamId1/Data", func(w http.ResponseWriter, r *http.Request) {
+		startIndex = r.URL.Query().Get("startIndex")
+		endIndex = r.URL.Query().Get("endIndex")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"Step": 1, "Batch": 7, "Value": 1.5}, {"Step": 2, "Batch": 7, "Value": 2.5}]`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	resp, err := StreamsDataQuery(&client, namespaceId, "token", "StreamId1", "7|1", "7|2", false)
+
+	expected := data.NewFrame("StreamName1",
+		data.NewField("Batch", nil, []int32{7, 7}),
+		data.NewField("Step", nil, []int32{1, 2}),
+		data.NewField("Value", nil, []float64{1.5, 2.5}),
+	).SetMeta(&data.FrameMeta{
+		Type:                data.FrameTypeTable,
+		TypeVersion:         data.FrameTypeVersion{0, 1},
+		ExecutedQueryString: server.URL + basePath + "/streams/StreamId1/Data?startIndex=7%7C1&endIndex=7%7C2",
+	})
+
+	if err != nil || !reflect.DeepEqual(resp, expected) {
+		t.Errorf("FAILED: expected %v, got %v (%v)\n", expected, resp, err)
+	}
+	if startIndex != "7|1" || endIndex != "7|2" {
+		t.Errorf("FAILED: expected index range 7|1 to 7|2, got %s to %s\n", startIndex, endIndex)
+	}
+
+	// the dashboard time range cannot be used with an integer index
+	_, err = StreamsDataQuery(&client, namespaceId, "token", "StreamId1", "2022-06-04T00:00:00Z", "2022-06-05T00:00:00Z", false)
+	if err == nil || !strings.Contains(err.Error(), "indexed by Int32 property Batch") {
+		t.Errorf("FAILED: expected an index error, got %v\n", err)
+	}
+}
+
+func TestStreamsDataQueryLabels(t *testing.T) {
+	basePath := "/api/" + apiVersion + "/tenants/" + tenantId + "/namespaces/" + namespaceId
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(basePath+"/streams/StreamId1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"TypeId": "StreamType1", "Id": "StreamId1", "Name": "StreamName1"}`))
+	})
+
+	mux.HandleFunc(basePath+"/types/StreamType1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"Id": "StreamType1",
+			"SdsTypeCode": 1,
+			"Properties": [
+				{"Id": "Timestamp", "IsKey": true, "SdsType": {"SdsTypeCode": 16}},
+				{"Id": "Value", "SdsType": {"SdsTypeCode": 14}}
+			]
+		}`))
+	})
+
+	labelRequests := 0
+	mux.HandleFunc(basePath+"/streams/StreamId1/Metadata", func(w http.ResponseWriter, r *http.Request) {
+		labelRequests++
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"site": "Houston", "unit": "A"}`))
+	})
+
+	mux.HandleFunc(basePath+"/streams/StreamId1/Tags", func(w http.ResponseWriter, r *http.Request) {
+		labelRequests++
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`["pump", "critical"]`))
+	})
+
+	mux.HandleFunc(basePath+"/streams/StreamId1/Data", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"Timestamp": "2022-06-04T00:00:00Z", "Value": 1}]`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// labels are only read when requested
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	resp, err := StreamsDataQuery(&client, namespaceId, "token", "StreamId1", "", "", false)
+	if err != nil {
+		t.Fatalf("FAILED: unexpected error %v\n", err)
+	}
+	if labelRequests != 0 || resp.Fields[1].Labels != nil {
+		t.Errorf("FAILED: expected no labels, got %v after %d requests\n", resp.Fields[1].Labels, labelRequests)
+	}
+
+	resp, err = StreamsDataQuery(&client, namespaceId, "token", "StreamId1", "", "", true)
+	if err != nil {
+		t.Fatalf("FAILED: unexpected error %v\n", err)
+	}
+
+	expected := data.Labels{"site": "Houston", "unit": "A", "tags": "pump,critical"}
+	if resp.Fields[0].Labels != nil {
+		t.Errorf("FAILED: expected no labels on the index field, got %v\n", resp.Fields[0].Labels)
+	}
+	if !reflect.DeepEqual(resp.Fields[1].Labels, expected) {
+		t.Errorf("FAILED: expected %v, got %v\n", expected, resp.Fields[1].Labels)
+	}
+}
+
+func TestStreamsDataQueryMissingLabels(t *testing.T) {
+	basePath := "/api/" + apiVersion + "/tenants/" + tenantId + "/namespaces/" + namespaceId
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(basePath+"/streams/StreamId1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"TypeId": "StreamType1", "Id": "StreamId1", "Name": "StreamName1"}`))
+	})
+
+	mux.HandleFunc(basePath+"/types/StreamType1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"Id": "StreamType1",
+			"SdsTypeCode": 1,
+			"Properties": [
+				{"Id": "Timestamp", "IsKey": true, "SdsType": {"SdsTypeCode": 16}},
+				{"Id": "Value", "SdsType": {"SdsTypeCode": 14}}
+			]
+		}`))
+	})
+
+	labelRequests := 0
+	for _, path := range []string{"/Metadata", "/Tags"} {
+		mux.HandleFunc(basePath+"/streams/StreamId1"+path, func(w http.ResponseWriter, r *http.Request) {
+			labelRequests++
+			w.WriteHeader(http.StatusNotFound)
+		})
+	}
+
+	mux.HandleFunc(basePath+"/streams/StreamId1/Data", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"Timestamp": "2022-06-04T00:00:00Z", "Value": 1}]`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// missing metadata and tags are empty, and cached
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	client.metadataCache = newMetadataCache(time.Minute)
+	for i := 0; i < 3; i++ {
+		resp, err := StreamsDataQuery(&client, namespaceId, "token", "StreamId1", "", "", true)
+		if err != nil {
+			t.Fatalf("FAILED: unexpected error %v\n", err)
+		}
+		if len(resp.Fields[1].Labels) != 0 {
+			t.Errorf("FAILED: expected no labels, got %v\n", resp.Fields[1].Labels)
+		}
+	}
+	if labelRequests != 2 {
+		t.Errorf("FAILED: expected 2 label requests, got %d\n", labelRequests)
+	}
+}
+
+func TestApplyPropertyOverrides(t *testing.T) {
+	sdsType := sds.SdsType{
+		Properties: []sds.SdsTypeProperty{
+			{Id: "Temp", Uom: "degree Celsius", SdsType: sds.SdsType{SdsTypeCode: "Double"}},
+			{Id: "Position", SdsType: sds.SdsType{SdsTypeCode: "Object", Properties: []sds.SdsTypeProperty{
+				{Id: "X", Uom: "meter", SdsType: sds.SdsType{SdsTypeCode: "Double"}},
+			}}},
+		},
+	}
+
+	overridden := applyPropertyOverrides(sdsType, []sds.SdsStreamPropertyOverride{
+		{SdsTypePropertyId: "Temp", Uom: "degree Fahrenheit"},
+		{SdsTypePropertyId: "Position.X", Uom: "foot"},
+	})
+	if overridden.Properties[0].Uom != "degree Fahrenheit" || overridden.Properties[1].SdsType.Properties[0].Uom != "foot" {
+		t.Errorf("FAILED: expected overridden units of measure, got %v\n", overridden.Properties)
+	}
+
+	// the type is left unchanged, since it may be shared with the metadata cache
+	if sdsType.Properties[0].Uom != "degree Celsius" || sdsType.Properties[1].SdsType.Properties[0].Uom != "meter" {
+		t.Errorf("FAILED: expected the type to be unchanged, got %v\n", sdsType.Properties)
+	}
+}
+
+func TestCreateDataFrameFromSdsDataFrameType(t *testing.T) {
+	timestamp := sds.SdsTypeProperty{Id: "Timestamp", IsKey: true, SdsType: sds.SdsType{SdsTypeCode: "DateTime"}}
+	value := sds.SdsTypeProperty{Id: "Value", SdsType: sds.SdsType{SdsTypeCode: "Double"}}
+
+	tests := []struct {
+		name       string
+		properties []sds.SdsTypeProperty
+		body       string
+		expected   data.FrameType
+	}{
+		{
+			name:       "time-index",
+			properties: []sds.SdsTypeProperty{timestamp, value, {Id: "Active", SdsType: sds.SdsType{SdsTypeCode: "Boolean"}}},
+			body:       `[{"Timestamp": "2022-06-04T00:00:00Z", "Value": 1, "Active": true}]`,
+			expected:   data.FrameTypeTimeSeriesWide,
+		},
+		{
+			name:       "compound-time-index",
+			properties: []sds.SdsTypeProperty{timestamp, {Id: "Channel", IsKey: true, Order: 1, SdsType: sds.SdsType{SdsTypeCode: "Int32"}}, value},
+			body:       `[{"Timestamp": "2022-06-04T00:00:00Z", "Channel": 1, "Value": 1}, {"Timestamp": "2022-06-04T00:00:00Z", "Channel": 2, "Value": 2}]`,
+			expected:   data.FrameTypeTable,
+		},
+		{
+			name:       "string-value",
+			properties: []sds.SdsTypeProperty{timestamp, {Id: "State", SdsType: sds.SdsType{SdsTypeCode: "String"}}},
+			body:       `[{"Timestamp": "2022-06-04T00:00:00Z", "State": "Running"}]`,
+			expected:   data.FrameTypeTable,
+		},
+		{
+			name:       "int-index",
+			properties: []sds.SdsTypeProperty{{Id: "Index", IsKey: true, SdsType: sds.SdsType{SdsTypeCode: "Int32"}}, value},
+			body:       `[{"Index": 1, "Value": 1}]`,
+			expected:   data.FrameTypeTable,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			frame, err := createDataFrameFromSdsData("test", sds.SdsType{Properties: test.properties}, []byte(test.body), "")
+			if err != nil || frame.Meta.Type != test.expected {
+				t.Errorf("FAILED: expected %v, got %v (%v)\n", test.expected, frame.Meta.Type, err)
+			}
+		})
+	}
+}
+
+var benchmarkType = sds.SdsType{
+	Id: "BenchmarkType",
+	Properties: []sds.SdsTypeProperty{
+		{Id: "Timestamp", IsKey: true, SdsType: sds.SdsType{SdsTypeCode: "DateTime"}},
+		{Id: "Value", SdsType: sds.SdsType{SdsTypeCode: "Double"}},
+		{Id: "Quality", SdsType: sds.SdsType{SdsTypeCode: "NullableInt32"}},
+	},
+}
+
+// Creates an SDS data response with the specified number of events of benchmarkType.
+func createBenchmarkSdsData(events int) []byte {
+	start := time.Date(2022, 6, 4, 0, 0, 0, 0, time.UTC)
+	var body bytes.Buffer
+	body.WriteString("[")
+	for i := 0; i < events; i++ {
+		if i > 0 {
+			body.WriteString(",")
+		}
+		fmt.Fprintf(&body, `{"Timestamp":"%s","Value":%d.5,"Quality":%d}`, start.Add(time.Duration(i)*time.Second).Format(time.RFC3339), i, i%3)
+	}
+	body.WriteString("]")
+	return body.Bytes()
+}
+
+func BenchmarkCreateDataFrameFromSdsData(b *testing.B) {
+	body := createBenchmarkSdsData(1000000)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		frame, err := createDataFrameFromSdsData("benchmark", benchmarkType, body, "")
+		if err != nil || frame.Rows() != 1000000 {
+			b.Fatalf("FAILED: expected 1000000 rows, got %v (%v)", frame.Rows(), err)
+		}
+	}
+}
+
+// Decodes the same response into maps and appends it row by row, as frames were built
+// before columnar decoding, for comparison with BenchmarkCreateDataFrameFromSdsData.
+func BenchmarkRowByRowDataFrame(b *testing.B) {
+	body := createBenchmarkSdsData(1000000)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var sdsData []map[string]interface{}
+		if err := json.Unmarshal(body, &sdsData); err != nil {
+			b.Fatal(err)
+		}
+
+		frame := data.NewFrame("benchmark",
+			data.NewField("Timestamp", nil, []time.Time{}),
+			data.NewField("Value", nil, []float64{}),
+			data.NewField("Quality", nil, []*int32{}),
+		)
+		for _, event := range sdsData {
+			timestamp, _ := time.Parse(time.RFC3339, event["Timestamp"].(string))
+			quality := int32(event["Quality"].(float64))
+			frame.AppendRow(timestamp, event["Value"].(float64), &quality)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package cds
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Methods a client authenticates with when requesting a client credentials token.
+const (
+	ClientSecretPost  = "client_secret_post"
+	ClientSecretBasic = "client_secret_basic"
+	PrivateKeyJwt     = "private_key_jwt"
+)
+
+// Type of the signed JWT sent as client assertion with the private_key_jwt method.
+const clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// Time a client assertion is valid for.
+const clientAssertionLifetime = 5 * time.Minute
+
+// Options of client credentials token requests. The certificate is a PEM encoded X.509
+// certificate and its private key, used to sign the client assertions of private_key_jwt.
+type TokenOptions struct {
+	AuthMethod  string
+	Scopes      string
+	Audience    string
+	Certificate string
+}
+
+// Creates the client credentials token request for a token endpoint, authenticated with
+// the configured method.
+func newTokenRequest(d *CdsClient, tokenEndpoint string) (*http.Request, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if d.tokenOptions.Scopes != "" {
+		form.Set("scope", d.tokenOptions.Scopes)
+	}
+	if d.tokenOptions.Audience != "" {
+		form.Set("audience", d.tokenOptions.Audience)
+	}
+
+	switch d.tokenOptions.AuthMethod {
+	case "", ClientSecretPost:
+		form.Set("client_id", d.clientId)
+		form.Set("client_secret", d.clientSecret)
+	case ClientSecretBasic:
+	case PrivateKeyJwt:
+		assertion, err := clientAssertion(d.clientId, tokenEndpoint, d.tokenOptions.Certificate, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		form.Set("client_id", d.clientId)
+		form.Set("client_assertion_type", clientAssertionType)
+		form.Set("client_assertion", assertion)
+	default:
+		return nil, fmt.Errorf("unknown client authentication method %s", d.tokenOptions.AuthMethod)
+	}
+
+	req, err := http.NewRequest("POST", tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// the credentials are form encoded before they are encoded as basic credentials
+	if d.tokenOptions.AuthMethod == ClientSecretBasic {
+		req.SetBasicAuth(url.QueryEscape(d.clientId), url.QueryEscape(d.clientSecret))
+	}
+
+	return req, nil
+}
+
+// Creates a client assertion for a token endpoint, signed with the private key of the
+// client certificate. The certificate is identified by its SHA-1 thumbprint.
+func clientAssertion(clientId string, tokenEndpoint string, certificatePem string, now time.Time) (string, error) {
+	key, certificate, err := parseClientCertificate(certificatePem)
+	if err != nil {
+		return "", err
+	}
+
+	jti := make([]byte, 16)
+	_, err = rand.Read(jti)
+	if err != nil {
+		return "", err
+	}
+
+	thumbprint := sha1.Sum(certificate.Raw)
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"x5t": base64.RawURLEncoding.EncodeToString(thumbprint[:]),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": clientId,
+		"sub": clientId,
+		"aud": tokenEndpoint,
+		"jti": hex.EncodeToString(jti),
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"exp": now.Add(clientAssertionLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Reads the certificate and RSA private key of a client from PEM blocks. The private key
+// may be a PKCS #1 or PKCS #8 key and must belong to the certificate.
+func parseClientCertificate(certificatePem string) (*rsa.PrivateKey, *x509.Certificate, error) {
+	var key *rsa.PrivateKey
+	var certificate *x509.Certificate
+
+	rest := []byte(certificatePem)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case "CERTIFICATE":
+			if certificate != nil {
+				continue
+			}
+			parsed, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid client certificate: %w", err)
+			}
+			certificate = parsed
+		case "RSA PRIVATE KEY":
+			parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid client private key: %w", err)
+			}
+			key = parsed
+		case "PRIVATE KEY":
+			parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid client private key: %w", err)
+			}
+			rsaKey, ok := parsed.(*rsa.PrivateKey)
+			if !ok {
+				return nil, nil, fmt.Errorf("the client private key must be an RSA key")
+			}
+			key = rsaKey
+		}
+	}
+
+	if certificate == nil {
+		return nil, nil, fmt.Errorf("the client certificate has no CERTIFICATE block")
+	}
+	if key == nil {
+		return nil, nil, fmt.Errorf("the client certificate has no RSA private key")
+	}
+	if !key.PublicKey.Equal(certificate.PublicKey) {
+		return nil, nil, fmt.Errorf("the client private key does not belong to the client certificate")
+	}
+
+	return key, certificate, nil
+}
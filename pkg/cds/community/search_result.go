@@ -0,0 +1,13 @@
+package community
+
+// Asset or data view shared with a community, with the tenant and namespace it belongs to.
+type SearchResult struct {
+	Id          string `json:"Id"`
+	Name        string `json:"Name"`
+	Description string `json:"Description"`
+	Self        string `json:"Self"`
+	TenantId    string `json:"TenantId"`
+	TenantName  string `json:"TenantName"`
+	NamespaceId string `json:"NamespaceId"`
+	CommunityId string `json:"CommunityId"`
+}
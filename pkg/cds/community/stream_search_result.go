@@ -7,6 +7,7 @@ type StreamSearchResult struct {
 	Description string `json:"Description"`
 	Self        string `json:"Self"`
 	TenantId    string `json:"TenantId"`
+	TenantName  string `json:"TenantName"`
 	NamespaceId string `json:"NamespaceId"`
 	CommunityId string `json:"CommunityId"`
 }
@@ -0,0 +1,66 @@
+package cds
+
+import (
+	"net/url"
+
+	"github.com/aveva/connect-data-services/pkg/cds/community"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Searches the assets shared with a community.
+func CommunityAssetsQuery(d *CdsClient, communityId string, token string, query string, options SearchOptions) (*data.Frame, error) {
+	return communitySearchQuery(d, communityId, token, "assets", query, options)
+}
+
+// Searches the data views shared with a community.
+func CommunityDataViewsQuery(d *CdsClient, communityId string, token string, query string, options SearchOptions) (*data.Frame, error) {
+	return communitySearchQuery(d, communityId, token, "dataviews", query, options)
+}
+
+// Searches a collection of a community, with the contributing tenant and namespace of
+// each result.
+func communitySearchQuery(d *CdsClient, communityId string, token string, collection string, query string, options SearchOptions) (*data.Frame, error) {
+	basePath := d.resource + "/api/" + d.apiVersion + "/search/communities/" + url.QueryEscape(communityId)
+	path := (basePath + "/" + collection + "?query=" + url.QueryEscape(query))
+
+	// the search API has no tenant filter, so results of other tenants are filtered out
+	var keep func(community.SearchResult) bool
+	if options.TenantId != "" {
+		keep = func(result community.SearchResult) bool {
+			return options.includesTenant(result.TenantId)
+		}
+	}
+
+	results, truncated, err := filteredSearchPages(d, token, path, nil, options, keep)
+	if err != nil {
+		return nil, err
+	}
+
+	// create property lists from the results contributed by the selected tenant
+	ids := make([]string, 0, len(results))
+	names := make([]string, 0, len(results))
+	descriptions := make([]string, 0, len(results))
+	tenantIds := make([]string, 0, len(results))
+	tenantNames := make([]string, 0, len(results))
+	namespaceIds := make([]string, 0, len(results))
+	for _, result := range results {
+		ids = append(ids, result.Self)
+		names = append(names, result.Name)
+		descriptions = append(descriptions, result.Description)
+		tenantIds = append(tenantIds, result.TenantId)
+		tenantNames = append(tenantNames, result.TenantName)
+		namespaceIds = append(namespaceIds, result.NamespaceId)
+	}
+
+	frame := data.NewFrame("response",
+		data.NewField("Id", nil, ids),
+		data.NewField("Name", nil, names),
+		data.NewField("Description", nil, descriptions),
+		data.NewField("TenantId", nil, tenantIds),
+		data.NewField("TenantName", nil, tenantNames),
+		data.NewField("NamespaceId", nil, namespaceIds),
+	)
+	setCatalogMeta(frame, truncated, len(results))
+
+	return frame, nil
+}
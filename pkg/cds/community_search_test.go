@@ -0,0 +1,128 @@
+package cds
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestCommunityAssetsQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/"+apiVersion+"/search/communities/"+communityId+"/assets" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[
+			{"Id": "Asset1", "Name": "Pump 1", "Self": "http://host/Asset1", "TenantId": "tenantId1", "TenantName": "Partner 1", "NamespaceId": "namespaceId1"},
+			{"Id": "Asset2", "Name": "Pump 2", "Self": "http://host/Asset2", "TenantId": "tenantId2", "TenantName": "Partner 2", "NamespaceId": "namespaceId2"}
+		]`))
+	}))
+	defer server.Close()
+
+	expected := data.NewFrame("response",
+		data.NewField("Id", nil, []string{"http://host/Asset2"}),
+		data.NewField("Name", nil, []string{"Pump 2"}),
+		data.NewField("Description", nil, []string{""}),
+		data.NewField("TenantId", nil, []string{"tenantId2"}),
+		data.NewField("TenantName", nil, []string{"Partner 2"}),
+		data.NewField("NamespaceId", nil, []string{"namespaceId2"}),
+	).SetMeta(&data.FrameMeta{Type: data.FrameTypeTable, TypeVersion: data.FrameTypeVersion{0, 1}})
+
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	resp, err := CommunityAssetsQuery(&client, communityId, "token", "", SearchOptions{TenantId: "TENANTID2"})
+	if err != nil || !reflect.DeepEqual(resp, expected) {
+		t.Errorf("FAILED: expected %v, got %v (%v)\n", expected, resp, err)
+	}
+}
+
+func TestCommunityAssetsQueryTenantPages(t *testing.T) {
+	// ten assets alternating between two tenants, paged by skip and count
+	pages := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+
+		var results []string
+		for i := skip; i < skip+count && i < 10; i++ {
+			results = append(results, fmt.Sprintf(`{"Id": "Asset%d", "Self": "http://host/Asset%d", "TenantId": "tenantId%d"}`, i, i, i%2+1))
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("[" + strings.Join(results, ",") + "]"))
+	}))
+	defer server.Close()
+
+	// pages are read until the count of results of the tenant is reached
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	resp, err := CommunityAssetsQuery(&client, communityId, "token", "", SearchOptions{Count: 3, TenantId: "tenantId2"})
+	expected := []string{"http://host/Asset1", "http://host/Asset3", "http://host/Asset5"}
+	if err != nil || !reflect.DeepEqual(resp.Fields[0].At(0), expected[0]) || resp.Rows() != len(expected) {
+		t.Fatalf("FAILED: expected %v, got %v (%v)\n", expected, resp, err)
+	}
+	for i, id := range expected {
+		if resp.Fields[0].At(i) != id {
+			t.Errorf("FAILED: expected %v, got %v\n", id, resp.Fields[0].At(i))
+		}
+	}
+	if pages != 2 || len(resp.Meta.Notices) != 0 {
+		t.Errorf("FAILED: expected 2 pages without notices, got %d pages and %v\n", pages, resp.Meta.Notices)
+	}
+
+	// every page is read when all pages are requested
+	resp, err = CommunityAssetsQuery(&client, communityId, "token", "", SearchOptions{Count: 3, AllPages: true, TenantId: "tenantId1"})
+	if err != nil || resp.Rows() != 5 {
+		t.Errorf("FAILED: expected 5 assets, got %v (%v)\n", resp, err)
+	}
+}
+
+func TestCommunityAssetsQueryTenantPageLimit(t *testing.T) {
+	// an endless search where only the first asset is contributed by the tenant
+	pages := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+
+		var results []string
+		for i := skip; i < skip+count; i++ {
+			tenant := "tenantId1"
+			if i == 0 {
+				tenant = "tenantId2"
+			}
+			results = append(results, fmt.Sprintf(`{"Id": "Asset%d", "Self": "http://host/Asset%d", "TenantId": "%s"}`, i, i, tenant))
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("[" + strings.Join(results, ",") + "]"))
+	}))
+	defer server.Close()
+
+	// the pages read are limited and the kept results are shown as truncated
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	resp, err := CommunityAssetsQuery(&client, communityId, "token", "", SearchOptions{Count: 3, TenantId: "tenantId2"})
+	if err != nil || resp.Rows() != 1 || pages != maxFilteredSearchPages {
+		t.Fatalf("FAILED: expected 1 asset in %d pages, got %v in %d pages (%v)\n", maxFilteredSearchPages, resp, pages, err)
+	}
+	if len(resp.Meta.Notices) != 1 || resp.Meta.Notices[0] != truncatedNotice(1) {
+		t.Errorf("FAILED: expected %v, got %v\n", truncatedNotice(1), resp.Meta.Notices)
+	}
+
+	// no truncation notice is shown when no result is kept
+	pages = 0
+	resp, err = CommunityAssetsQuery(&client, communityId, "token", "", SearchOptions{Skip: 1, Count: 3, TenantId: "tenantId2"})
+	if err != nil || resp.Rows() != 0 || pages != maxFilteredSearchPages {
+		t.Fatalf("FAILED: expected no assets in %d pages, got %v in %d pages (%v)\n", maxFilteredSearchPages, resp, pages, err)
+	}
+	if resp.Meta != nil && len(resp.Meta.Notices) != 0 {
+		t.Errorf("FAILED: expected no notices, got %v\n", resp.Meta.Notices)
+	}
+}
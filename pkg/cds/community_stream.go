@@ -0,0 +1,68 @@
+package cds
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Identifies a stream shared with a community by the tenant and namespace that contributed it.
+// Community streams are identified by their Self links, which are parsed into a reference
+// rather than requested as is, so that requests are only sent to the configured resource.
+type communityStreamRef struct {
+	TenantId    string
+	NamespaceId string
+	StreamId    string
+}
+
+// Parses the Self link of a community stream. The link must be hosted by the resource of
+// the client, and may use any API version. Relative links are resolved against the resource.
+func parseCommunityStreamRef(d *CdsClient, self string) (communityStreamRef, error) {
+	parsed, err := resolveResourceLink(d, self)
+	if err != nil {
+		return communityStreamRef{}, fmt.Errorf("invalid community stream: %w", err)
+	}
+
+	// the path ends with api/{version}/tenants/{tenantId}/namespaces/{namespaceId}/streams/{streamId}
+	segments := strings.Split(strings.TrimSuffix(parsed.EscapedPath(), "/"), "/")
+	n := len(segments)
+	if n < 8 ||
+		!strings.EqualFold(segments[n-8], "api") ||
+		!strings.EqualFold(segments[n-6], "tenants") ||
+		!strings.EqualFold(segments[n-4], "namespaces") ||
+		!strings.EqualFold(segments[n-2], "streams") {
+		return communityStreamRef{}, fmt.Errorf("community stream %s is not a stream link", self)
+	}
+
+	values := make([]string, 0, 3)
+	for _, segment := range []string{segments[n-5], segments[n-3], segments[n-1]} {
+		value, err := url.PathUnescape(segment)
+		if err != nil || value == "" {
+			return communityStreamRef{}, fmt.Errorf("community stream %s is not a stream link", self)
+		}
+		values = append(values, value)
+	}
+
+	return communityStreamRef{
+		TenantId:    values[0],
+		NamespaceId: values[1],
+		StreamId:    values[2],
+	}, nil
+}
+
+// Returns the path of the stream on the resource and API version of the client. The ids are
+// path escaped, as in the Self link, since SDS reads a + in a path as a literal +.
+func (r communityStreamRef) path(d *CdsClient) string {
+	return d.resource + "/api/" + d.apiVersion + "/tenants/" + url.PathEscape(r.TenantId) +
+		"/namespaces/" + url.PathEscape(r.NamespaceId) + "/streams/" + url.PathEscape(r.StreamId)
+}
+
+// Returns the tenant and namespace that contributed the stream as labels.
+func (r communityStreamRef) labels() data.Labels {
+	return data.Labels{
+		"tenantId":    r.TenantId,
+		"namespaceId": r.NamespaceId,
+	}
+}
@@ -0,0 +1,74 @@
+package cds
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCommunityStreamRef(t *testing.T) {
+	client := NewCdsClient("https://example.com", "v2", tenantId, "", "")
+
+	tests := []struct {
+		name     string
+		self     string
+		expected communityStreamRef
+		path     string
+		valid    bool
+	}{
+		{
+			name:     "any-api-version",
+			self:     "https://example.com/api/v1-preview/tenants/tenant1/namespaces/namespace1/streams/stream1",
+			expected: communityStreamRef{TenantId: "tenant1", NamespaceId: "namespace1", StreamId: "stream1"},
+			path:     "https://example.com/api/v2/tenants/tenant1/namespaces/namespace1/streams/stream1",
+			valid:    true,
+		},
+		{
+			name:     "escaped-stream-id",
+			self:     "https://EXAMPLE.com/api/v1/Tenants/tenant1/Namespaces/namespace1/Streams/stream%3F1",
+			expected: communityStreamRef{TenantId: "tenant1", NamespaceId: "namespace1", StreamId: "stream?1"},
+			path:     "https://example.com/api/v2/tenants/tenant1/namespaces/namespace1/streams/stream%3F1",
+			valid:    true,
+		},
+		{
+			name: "other-host",
+			self: "https://attacker.example/api/v1/tenants/tenant1/namespaces/namespace1/streams/stream1",
+		},
+		{
+			name: "other-scheme",
+			self: "http://example.com/api/v1/tenants/tenant1/namespaces/namespace1/streams/stream1",
+		},
+		{
+			name:     "relative",
+			self:     "/api/v1/tenants/tenant1/namespaces/namespace1/streams/stream1",
+			expected: communityStreamRef{TenantId: "tenant1", NamespaceId: "namespace1", StreamId: "stream1"},
+			path:     "https://example.com/api/v2/tenants/tenant1/namespaces/namespace1/streams/stream1",
+			valid:    true,
+		},
+		{
+			name: "scheme-relative-other-host",
+			self: "//attacker.example/api/v1/tenants/tenant1/namespaces/namespace1/streams/stream1",
+		},
+		{
+			name: "not-a-stream",
+			self: "https://example.com/api/v1/tenants/tenant1/namespaces/namespace1/types/type1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ref, err := parseCommunityStreamRef(&client, test.self)
+			if (err == nil) != test.valid {
+				t.Fatalf("FAILED: expected valid %v, got %v\n", test.valid, err)
+			}
+			if !test.valid {
+				return
+			}
+			if !reflect.DeepEqual(ref, test.expected) {
+				t.Errorf("FAILED: expected %v, got %v\n", test.expected, ref)
+			}
+			if ref.path(&client) != test.path {
+				t.Errorf("FAILED: expected %v, got %v\n", test.path, ref.path(&client))
+			}
+		})
+	}
+}
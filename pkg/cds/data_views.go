@@ -0,0 +1,213 @@
+package cds
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/aveva/connect-data-services/pkg/cds/dataviews"
+	"github.com/aveva/connect-data-services/pkg/cds/sds"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Upper bound on the pages of data view data read for one query.
+const maxDataViewPages = 100
+
+// Matches the next page link of a Link header.
+var nextLinkPattern = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+
+// Lists the data views of a namespace.
+func DataViewsQuery(d *CdsClient, namespaceId string, token string, options SearchOptions) (*data.Frame, error) {
+	basePath := d.resource + "/api/" + d.apiVersion + "/tenants/" + url.QueryEscape(d.tenantId) + "/namespaces/" + url.QueryEscape(namespaceId)
+	path := (basePath + "/dataviews")
+
+	dataViews, truncated, err := searchPages[dataviews.DataView](d, token, path, nil, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// create property lists from data views list
+	ids := make([]string, len(dataViews))
+	names := make([]string, len(dataViews))
+	descriptions := make([]string, len(dataViews))
+	indexTypeCodes := make([]string, len(dataViews))
+	defaultIntervals := make([]string, len(dataViews))
+	for i, dataView := range dataViews {
+		ids[i] = dataView.Id
+		names[i] = dataView.Name
+		descriptions[i] = dataView.Description
+		indexTypeCodes[i] = dataView.IndexTypeCode
+		defaultIntervals[i] = dataView.DefaultInterval
+	}
+
+	frame := data.NewFrame("response",
+		data.NewField("Id", nil, ids),
+		data.NewField("Name", nil, names),
+		data.NewField("Description", nil, descriptions),
+		data.NewField("IndexTypeCode", nil, indexTypeCodes),
+		data.NewField("DefaultInterval", nil, defaultIntervals),
+	)
+	setCatalogMeta(frame, truncated, len(ids))
+
+	return frame, nil
+}
+
+// Reads the interpolated data of a data view between two indexes, following the next
+// page links of the responses, and converts it into a wide frame.
+func DataViewDataQuery(d *CdsClient, namespaceId string, token string, id string, startIndex string, endIndex string, interval string) (*data.Frame, error) {
+	basePath := d.resource + "/api/" + d.apiVersion + "/tenants/" + url.QueryEscape(d.tenantId) + "/namespaces/" + url.QueryEscape(namespaceId)
+	path := (basePath + "/dataviews/" + url.QueryEscape(id) + "/data/interpolated?startIndex=" + url.QueryEscape(startIndex) +
+		"&endIndex=" + url.QueryEscape(endIndex) + "&interval=" + url.QueryEscape(interval) + "&form=table")
+
+	var table *dataViewColumns
+	next := path
+	truncated := false
+	for page := 0; next != ""; page++ {
+		if page == maxDataViewPages {
+			truncated = true
+			break
+		}
+
+		body, resp, err := sdsRequest(d, token, next, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result dataviews.DataViewTable
+		err = json.Unmarshal(body, &result)
+		if err != nil {
+			log.DefaultLogger.Warn("Error parsing json", err.Error())
+			return nil, err
+		}
+
+		if table == nil {
+			table = newDataViewColumns(result.Columns)
+		}
+		if err = table.appendRows(result.Rows); err != nil {
+			return nil, err
+		}
+
+		next, err = nextPageLink(d, resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return table.frame(id, path, truncated), nil
+}
+
+// Returns the next page link of a response, or an empty string on the last page. The link
+// is resolved against the resource, and links to other hosts are rejected.
+func nextPageLink(d *CdsClient, resp *http.Response) (string, error) {
+	for _, link := range resp.Header.Values("Link") {
+		if match := nextLinkPattern.FindStringSubmatch(link); match != nil {
+			next, err := resolveResourceLink(d, match[1])
+			if err != nil {
+				return "", fmt.Errorf("invalid next page: %w", err)
+			}
+			return next.String(), nil
+		}
+	}
+
+	return "", nil
+}
+
+// Columns of data view data. Columns other than the index are nullable, since the
+// interpolated data of a field may be missing.
+type dataViewColumns struct {
+	names   []string
+	indexed bool
+	columns []sdsColumn
+}
+
+func newDataViewColumns(columns []dataviews.DataViewColumn) *dataViewColumns {
+	c := &dataViewColumns{
+		names:   make([]string, len(columns)),
+		columns: make([]sdsColumn, len(columns)),
+	}
+
+	for i, column := range columns {
+		code := sds.SdsTypeCode(column.Type)
+		if i == 0 {
+			c.indexed = code == "DateTime" || code == "DateTimeOffset"
+		} else {
+			code = code.Nullable()
+		}
+
+		c.names[i] = column.Name
+		c.columns[i] = newSdsColumn(code, 0)
+	}
+
+	return c
+}
+
+// Appends rows of data view data, where each row has a value per column.
+func (c *dataViewColumns) appendRows(rows [][]json.RawMessage) error {
+	for _, row := range rows {
+		if len(row) != len(c.columns) {
+			return fmt.Errorf("data view row has %d values, expected %d", len(row), len(c.columns))
+		}
+
+		for i, value := range row {
+			decoder := json.NewDecoder(bytes.NewReader(value))
+			decoder.UseNumber()
+			if err := decodeInto(decoder, c.columns[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Creates a frame from the data view data, with a notice when pages were left out. Data
+// indexed by time with numeric or boolean values is a wide time series, other data is a table.
+func (c *dataViewColumns) frame(name string, executedQuery string, truncated bool) *data.Frame {
+	frame := data.NewFrame(name)
+	frame.Meta = &data.FrameMeta{
+		Type:                data.FrameTypeTimeSeriesWide,
+		TypeVersion:         data.FrameTypeVersion{0, 1},
+		ExecutedQueryString: executedQuery,
+	}
+
+	for i, column := range c.columns {
+		frame.Fields = append(frame.Fields, column.fields(fieldPath{name: c.names[i], displayName: c.names[i]})...)
+		frame.Meta.Notices = append(frame.Meta.Notices, column.notices(c.names[i])...)
+	}
+
+	// data views are classified like streams, text values cannot be plotted
+	if !c.indexed || !hasTimeSeriesValues(frame.Fields) {
+		frame.Meta.Type = data.FrameTypeTable
+	}
+
+	if truncated {
+		frame.Meta.Notices = append(frame.Meta.Notices, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Only the first %d pages of data are shown, use a shorter time range or a longer interval", maxDataViewPages),
+		})
+	}
+
+	return frame
+}
+
+// Formats a duration as a TimeSpan, such as 1.02:03:04 for a day, two hours, three
+// minutes and four seconds.
+func formatTimeSpan(duration time.Duration) string {
+	if duration < time.Second {
+		duration = time.Second
+	}
+
+	seconds := int64(duration / time.Second)
+	days, seconds := seconds/86400, seconds%86400
+	timeSpan := fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds%3600/60, seconds%60)
+	if days > 0 {
+		timeSpan = fmt.Sprintf("%d.%s", days, timeSpan)
+	}
+
+	return timeSpan
+}
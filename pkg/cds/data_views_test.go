@@ -0,0 +1,119 @@
+package cds
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aveva/connect-data-services/pkg/cds/dataviews"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestDataViewDataQuery(t *testing.T) {
+	basePath := "/api/" + apiVersion + "/tenants/" + tenantId + "/namespaces/" + namespaceId
+	mux := http.NewServeMux()
+
+	// two pages of data, linked by a continuation token
+	var server *httptest.Server
+	var intervals []string
+	mux.HandleFunc(basePath+"/dataviews/DataView1/data/interpolated", func(w http.ResponseWriter, r *http.Request) {
+		intervals = append(intervals, r.URL.Query().Get("interval"))
+		columns := `"Columns": [{"Name": "Timestamp", "Type": "DateTime"}, {"Name": "Pump1.Flow", "Type": "Double"}, {"Name": "Pump1.State", "Type": "String"}]`
+
+		if r.URL.Query().Get("continuationToken") == "" {
+			w.Header().Add("Link", `<`+server.URL+r.URL.Path+`?continuationToken=page2>; rel="next", <`+server.URL+r.URL.Path+`>; rel="first"`)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{` + columns + `, "Rows": [["2022-06-04T00:00:00Z", 1.5, "On"]]}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{` + columns + `, "Rows": [["2022-06-04T00:01:00Z", null, null]]}`))
+	})
+
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	flow, state := 1.5, "On"
+	expected := data.NewFrame("DataView1",
+		data.NewField("Timestamp", nil, []time.Time{time.Date(2022, 6, 4, 0, 0, 0, 0, time.UTC), time.Date(2022, 6, 4, 0, 1, 0, 0, time.UTC)}),
+		data.NewField("Pump1.Flow", nil, []*float64{&flow, nil}),
+		data.NewField("Pump1.State", nil, []*string{&state, nil}),
+	).SetMeta(&data.FrameMeta{
+		Type:                data.FrameTypeTable,
+		TypeVersion:         data.FrameTypeVersion{0, 1},
+		ExecutedQueryString: server.URL + basePath + "/dataviews/DataView1/data/interpolated?startIndex=2022-06-04T00%3A00%3A00Z&endIndex=2022-06-04T00%3A01%3A00Z&interval=00%3A01%3A00&form=table",
+	})
+
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	resp, err := DataViewDataQuery(&client, namespaceId, "token", "DataView1", "2022-06-04T00:00:00Z", "2022-06-04T00:01:00Z", formatTimeSpan(time.Minute))
+	if err != nil || !reflect.DeepEqual(resp, expected) {
+		t.Errorf("FAILED: expected %v, got %v (%v)\n", expected, resp, err)
+	}
+	if len(intervals) != 2 {
+		t.Errorf("FAILED: expected 2 pages, got %d\n", len(intervals))
+	}
+}
+
+func TestDataViewDataQueryForeignNextPage(t *testing.T) {
+	// a next page link to another host, which must not receive the token
+	var foreignRequests int
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		foreignRequests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer foreign.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", `<`+foreign.URL+r.URL.Path+`?continuationToken=page2>; rel="next"`)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"Columns": [{"Name": "Timestamp", "Type": "DateTime"}], "Rows": [["2022-06-04T00:00:00Z"]]}`))
+	}))
+	defer server.Close()
+
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	resp, err := DataViewDataQuery(&client, namespaceId, "token", "DataView1", "2022-06-04T00:00:00Z", "2022-06-04T00:01:00Z", formatTimeSpan(time.Minute))
+	if err == nil || resp != nil {
+		t.Errorf("FAILED: expected an error for a foreign next page, got %v\n", resp)
+	}
+	if foreignRequests != 0 {
+		t.Errorf("FAILED: expected no requests to the foreign host, got %d\n", foreignRequests)
+	}
+}
+
+func TestDataViewFrameType(t *testing.T) {
+	tests := []struct {
+		name     string
+		columns  []dataviews.DataViewColumn
+		expected data.FrameType
+	}{
+		{"numeric", []dataviews.DataViewColumn{{Name: "Timestamp", Type: "DateTime"}, {Name: "Flow", Type: "Double"}, {Name: "Running", Type: "Boolean"}}, data.FrameTypeTimeSeriesWide},
+		{"text", []dataviews.DataViewColumn{{Name: "Timestamp", Type: "DateTime"}, {Name: "Flow", Type: "Double"}, {Name: "State", Type: "String"}}, data.FrameTypeTable},
+		{"not time indexed", []dataviews.DataViewColumn{{Name: "Depth", Type: "Double"}, {Name: "Pressure", Type: "Double"}}, data.FrameTypeTable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			frame := newDataViewColumns(test.columns).frame("DataView1", "", false)
+			if frame.Meta.Type != test.expected {
+				t.Errorf("FAILED: expected %v, got %v\n", test.expected, frame.Meta.Type)
+			}
+		})
+	}
+}
+
+func TestFormatTimeSpan(t *testing.T) {
+	tests := map[time.Duration]string{
+		time.Millisecond: "00:00:01",
+		90 * time.Second: "00:01:30",
+		26*time.Hour + 3*time.Minute + 4*time.Second: "1.02:03:04",
+	}
+
+	for duration, expected := range tests {
+		if timeSpan := formatTimeSpan(duration); timeSpan != expected {
+			t.Errorf("FAILED: expected %s, got %s\n", expected, timeSpan)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -26,25 +27,115 @@ var (
 )
 
 type CdsDataSource struct {
-	cdsClient *CdsClient
-	settings  *models.CdsSettings
+	cdsClient        *CdsClient
+	settings         *models.CdsSettings
+	resultCache      *queryCache[*data.Frame]
+	incrementalCache *queryCache[*incrementalEntry]
 }
 
 type CdsDataSourceOptions struct {
-	Resource      string `json:"resource"`
-	ApiVersion    string `json:"apiVersion"`
-	TenantId      string `json:"tenantId"`
-	NamespaceId   string `json:"namespaceId"`
-	UseCommunity  bool   `json:"useCommunity"`
-	CommunityId   string `json:"communityId"`
-	ClientId      string `json:"clientId"`
-	OauthPassThru bool   `json:"oauthPassThru"`
+	Type               string   `json:"type"`
+	EdsHost            string   `json:"edsHost"`
+	EdsPort            string   `json:"edsPort"`
+	Resource           string   `json:"resource"`
+	ApiVersion         string   `json:"apiVersion"`
+	TenantId           string   `json:"tenantId"`
+	NamespaceId        string   `json:"namespaceId"`
+	NamespaceIds       []string `json:"namespaceIds"`
+	UseCommunity       bool     `json:"useCommunity"`
+	CommunityId        string   `json:"communityId"`
+	ClientId           string   `json:"clientId"`
+	OauthPassThru      bool     `json:"oauthPassThru"`
+	CacheTtl           int      `json:"cacheTtl"`
+	CacheSize          int      `json:"cacheSize"`
+	MetadataCacheTtl   int      `json:"metadataCacheTtl"`
+	IncrementalQueries bool     `json:"incrementalQueries"`
 }
 
 type QueryModel struct {
-	Collection string `json:"collection"`
-	Query      string `json:"queryText"`
-	Id         string `json:"id"`
+	Collection        string   `json:"collection"`
+	Query             string   `json:"queryText"`
+	Id                string   `json:"id"`
+	BooleansAsNumbers bool     `json:"booleansAsNumbers"`
+	StartIndex        string   `json:"startIndex"`
+	EndIndex          string   `json:"endIndex"`
+	TargetUoms        []string `json:"targetUoms"`
+	Skip              int      `json:"skip"`
+	Count             int      `json:"count"`
+	OrderBy           string   `json:"orderBy"`
+	AllPages          bool     `json:"allPages"`
+	IncludeMetadata   bool     `json:"includeMetadata"`
+	IncludeLabels     bool     `json:"includeLabels"`
+	Interval          string   `json:"interval"`
+	LastValue         bool     `json:"lastValue"`
+	TenantId          string   `json:"tenantId"`
+	NamespaceId       string   `json:"namespaceId"`
+}
+
+// Determines whether the query reads a user supplied index range instead of the
+// dashboard time range.
+func (qm QueryModel) hasIndexRange() bool {
+	return qm.StartIndex != "" || qm.EndIndex != ""
+}
+
+// Returns the stream id a query is cached under. Queries with labels are cached apart
+// from queries without them.
+func (qm QueryModel) cacheStreamId() string {
+	if qm.IncludeLabels {
+		return qm.Id + "|labels"
+	}
+	return qm.Id
+}
+
+// Returns the paging, ordering and columns of a stream search.
+func (qm QueryModel) searchOptions() SearchOptions {
+	return SearchOptions{
+		Skip:            qm.Skip,
+		Count:           qm.Count,
+		OrderBy:         qm.OrderBy,
+		AllPages:        qm.AllPages,
+		IncludeMetadata: qm.IncludeMetadata,
+		TenantId:        qm.TenantId,
+	}
+}
+
+// Returns the stream of a streams/{id}/type collection. The id is taken from the query
+// when the collection contains the {id} placeholder.
+func streamTypeCollection(qm QueryModel) (string, bool) {
+	parts := strings.Split(qm.Collection, "/")
+	if len(parts) != 3 || !strings.EqualFold(parts[0], "streams") || !strings.EqualFold(parts[2], "type") {
+		return "", false
+	}
+
+	if parts[1] == "{id}" || parts[1] == "" {
+		return qm.Id, qm.Id != ""
+	}
+
+	id, err := url.PathUnescape(parts[1])
+	return id, err == nil && id != ""
+}
+
+// Returns the index range of the query. A missing start or end index defaults to the
+// start or end of the dashboard time range, so that a range is never sent half open.
+func (qm QueryModel) indexRange(timeRange backend.TimeRange) (string, string) {
+	startIndex := timeRange.From.Format(time.RFC3339)
+	if qm.StartIndex != "" {
+		startIndex = interpolateIndex(qm.StartIndex, timeRange)
+	}
+
+	endIndex := timeRange.To.Format(time.RFC3339)
+	if qm.EndIndex != "" {
+		endIndex = interpolateIndex(qm.EndIndex, timeRange)
+	}
+
+	return startIndex, endIndex
+}
+
+// Replaces the $__from and $__to placeholders in an index with the dashboard time range,
+// so that compound indexes starting with a timestamp can use it, as in $__from|1.
+func interpolateIndex(index string, timeRange backend.TimeRange) string {
+	index = strings.ReplaceAll(index, "$__from", timeRange.From.UTC().Format(time.RFC3339))
+	return strings.ReplaceAll(index, "$__to", timeRange.To.UTC().Format(time.RFC3339))
 }
 
 type CheckHealthResponseBody struct {
@@ -59,11 +150,40 @@ func NewCdsDataSource(ctx context.Context, dis backend.DataSourceInstanceSetting
 		return nil, err
 	}
 
-	client := NewCdsClient(settings.Resource, settings.ApiVersion, settings.TenantId, settings.ClientId, settings.Secrets.ClientSecret)
-	return &CdsDataSource{
+	var client CdsClient
+	if settings.IsEds() {
+		client, err = NewEdsClient(settings.EdsHost, settings.EdsPort)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		client = NewCdsClient(settings.Resource, settings.ApiVersion, settings.TenantId, settings.ClientId, settings.Secrets.ClientSecret)
+		client.tokenOptions = TokenOptions{
+			AuthMethod:  settings.ClientAuthMethod,
+			Scopes:      settings.Scopes,
+			Audience:    settings.Audience,
+			Certificate: settings.Secrets.ClientCertificate,
+		}
+	}
+	if settings.MetadataCacheTtl > 0 {
+		client.metadataCache = newMetadataCache(time.Duration(settings.MetadataCacheTtl) * time.Second)
+	}
+
+	dataSource := &CdsDataSource{
 		cdsClient: &client,
 		settings:  settings,
-	}, nil
+	}
+
+	// the query cache is only enabled when a ttl is configured
+	if settings.CacheTtl > 0 {
+		dataSource.resultCache = newQueryCache[*data.Frame](settings.CacheSize, time.Duration(settings.CacheTtl)*time.Second)
+	}
+
+	if settings.IncrementalQueries {
+		dataSource.incrementalCache = newQueryCache[*incrementalEntry](settings.CacheSize, incrementalIdleTimeout)
+	}
+
+	return dataSource, nil
 }
 
 // Dispose here tells plugin SDK that plugin wants to clean up resources when a new instance
@@ -77,20 +197,11 @@ func (d *CdsDataSource) Dispose() {
 func (d *CdsDataSource) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
 	log.DefaultLogger.Info("QueryData called", "request", req)
 
-	// retrieve token
-	var token string
-	if d.settings.OauthPassThru {
-		token = req.Headers["Authorization"]
-		if len(token) == 0 {
-			return nil, fmt.Errorf("Unable to retrieve token")
-		}
-	} else {
-		var err error
-		token, err = GetClientToken(d.cdsClient)
-		if err != nil {
-			log.DefaultLogger.Warn("Unable to retrieve token", err.Error())
-			return nil, err
-		}
+	// retrieve token, reporting a missing or expired identity as the error of each query
+	token, err := d.requestToken(req.GetHTTPHeader, isBackgroundRequest(req.PluginContext))
+	if err != nil {
+		log.DefaultLogger.Warn("Unable to retrieve token", err.Error())
+		return errorResponses(req, err), nil
 	}
 
 	// create response struct
@@ -112,106 +223,262 @@ func (d *CdsDataSource) QueryData(ctx context.Context, req *backend.QueryDataReq
 	return response, nil
 }
 
+// Returns a response with the same error for each query of a request.
+func errorResponses(req *backend.QueryDataRequest, err error) *backend.QueryDataResponse {
+	response := backend.NewQueryDataResponse()
+	for _, q := range req.Queries {
+		response.Responses[q.RefID] = backend.DataResponse{Error: err}
+	}
+
+	return response
+}
+
 // Handles the individual queries from QueryData.
 func (d *CdsDataSource) query(_ context.Context, pCtx backend.PluginContext, query backend.DataQuery, token string) (backend.DataResponse, error) {
 	log.DefaultLogger.Info("Running query", "query", query)
 	response := backend.DataResponse{}
 
-	// unmarshal the JSON into our QueryModel.
-	var qm QueryModel
+	// unmarshal the JSON into our QueryModel. Edge Data Store queries return booleans as
+	// numbers unless the query says otherwise, as they did when they were made by the browser.
+	qm := QueryModel{BooleansAsNumbers: d.settings.IsEds()}
 
 	response.Error = json.Unmarshal(query.JSON, &qm)
 	if response.Error != nil {
 		return response, nil
 	}
 
+	// resolve the namespace of the query before it is used by the cache or any request
+	qm.NamespaceId, response.Error = d.queryNamespace(qm)
+	if response.Error != nil {
+		return response, nil
+	}
+
+	// serve stream data queries from the query cache when possible
+	cacheKey, cacheable := d.queryCacheKey(qm, query.TimeRange, token)
+	if cacheable {
+		if frame, ok := d.resultCache.get(cacheKey); ok {
+			log.DefaultLogger.Debug("Query cache hit", "key", cacheKey)
+			response.Frames = append(response.Frames, transformFrame(qm, frame))
+			return response, nil
+		}
+	}
+
 	// determine what type of query to use
 	frame := data.NewFrame("response")
 	var err error
-	if d.settings.UseCommunity {
-		if strings.EqualFold(qm.Collection, "streams") && qm.Id != "" {
-			log.DefaultLogger.Debug("Community stream data query")
-			frame, err = CommunityStreamsDataQuery(d.cdsClient,
-				d.settings.CommunityId,
-				token,
-				qm.Id,
-				query.TimeRange.From.Format(time.RFC3339),
-				query.TimeRange.To.Format(time.RFC3339))
-		} else if strings.EqualFold(qm.Collection, "streams") {
-			log.DefaultLogger.Debug("Community stream query")
-			frame, err = CommunityStreamsQuery(d.cdsClient, d.settings.CommunityId, token, qm.Query)
+	collection := strings.ToLower(qm.Collection)
+	if collection == "" {
+		collection = "streams"
+	}
+
+	// Edge Data Store only stores streams and types
+	if d.settings.IsEds() && (collection == "dataviews" || collection == "assets" || collection == "assetstatus") {
+		response.Error = fmt.Errorf("the %s collection is not available for Edge Data Store", qm.Collection)
+		return response, nil
+	}
+
+	if collection == "streams" && qm.Id != "" {
+		if qm.hasIndexRange() {
+			startIndex, endIndex := qm.indexRange(query.TimeRange)
+			frame, err = d.streamsIndexQuery(qm, startIndex, endIndex, token)
+		} else if d.incrementalCache != nil && isLiveRange(query.TimeRange, time.Now()) {
+			frame, err = d.incrementalStreamsDataQuery(qm, query.TimeRange, token)
+		} else {
+			frame, err = d.streamsDataQuery(qm, query.TimeRange.From, query.TimeRange.To, token)
 		}
-	} else {
-		if strings.EqualFold(qm.Collection, "streams") && qm.Id != "" {
-			log.DefaultLogger.Debug("Stream data query")
-			frame, err = StreamsDataQuery(d.cdsClient,
-				d.settings.NamespaceId,
-				token,
-				qm.Id,
-				query.TimeRange.From.Format(time.RFC3339),
-				query.TimeRange.To.Format(time.RFC3339))
-		} else if strings.EqualFold(qm.Collection, "streams") {
+	} else if collection == "streams" {
+		if d.settings.UseCommunity {
+			log.DefaultLogger.Debug("Community stream query")
+			frame, err = CommunityStreamsQuery(d.cdsClient, d.settings.CommunityId, token, qm.Query, qm.searchOptions())
+		} else {
 			log.DefaultLogger.Debug("Stream query")
-			frame, err = StreamsQuery(d.cdsClient, d.settings.NamespaceId, token, qm.Query)
+			frame, err = StreamsQuery(d.cdsClient, qm.NamespaceId, token, qm.Query, qm.searchOptions())
+		}
+	} else if id, ok := streamTypeCollection(qm); ok {
+		if d.settings.UseCommunity {
+			log.DefaultLogger.Debug("Community stream type query")
+			frame, err = CommunityStreamTypeQuery(d.cdsClient, d.settings.CommunityId, token, id)
+		} else {
+			log.DefaultLogger.Debug("Stream type query")
+			frame, err = StreamTypeQuery(d.cdsClient, qm.NamespaceId, token, id)
+		}
+	} else if collection == "types" || collection == "streamviews" {
+		if d.settings.UseCommunity {
+			response.Error = fmt.Errorf("the %s collection is not available for communities", qm.Collection)
+			return response, nil
+		}
+
+		if collection == "types" {
+			log.DefaultLogger.Debug("Type query")
+			frame, err = TypesQuery(d.cdsClient, qm.NamespaceId, token, qm.Query, qm.searchOptions())
+		} else {
+			log.DefaultLogger.Debug("Stream view query")
+			frame, err = StreamViewsQuery(d.cdsClient, qm.NamespaceId, token, qm.Query, qm.searchOptions())
+		}
+	} else if collection == "dataviews" {
+		if d.settings.UseCommunity && qm.Id != "" {
+			response.Error = fmt.Errorf("reading %s by id is not available for communities", qm.Collection)
+			return response, nil
+		}
+
+		if d.settings.UseCommunity {
+			log.DefaultLogger.Debug("Community data view query")
+			frame, err = CommunityDataViewsQuery(d.cdsClient, d.settings.CommunityId, token, qm.Query, qm.searchOptions())
+		} else if qm.Id == "" {
+			log.DefaultLogger.Debug("Data view query")
+			frame, err = DataViewsQuery(d.cdsClient, qm.NamespaceId, token, qm.searchOptions())
+		} else {
+			log.DefaultLogger.Debug("Data view data query")
+			startIndex, endIndex := qm.indexRange(query.TimeRange)
+
+			interval := qm.Interval
+			if interval == "" {
+				interval = formatTimeSpan(query.Interval)
+			}
+
+			frame, err = DataViewDataQuery(d.cdsClient, qm.NamespaceId, token, qm.Id, startIndex, endIndex, interval)
 		}
+	} else if collection == "assets" {
+		if d.settings.UseCommunity && qm.Id != "" {
+			response.Error = fmt.Errorf("reading %s by id is not available for communities", qm.Collection)
+			return response, nil
+		}
+
+		if d.settings.UseCommunity {
+			log.DefaultLogger.Debug("Community asset query")
+			frame, err = CommunityAssetsQuery(d.cdsClient, d.settings.CommunityId, token, qm.Query, qm.searchOptions())
+		} else if qm.Id == "" {
+			log.DefaultLogger.Debug("Asset query")
+			frame, err = AssetsQuery(d.cdsClient, qm.NamespaceId, token, qm.Query, qm.searchOptions())
+		} else {
+			log.DefaultLogger.Debug("Asset data query")
+			startIndex, endIndex := qm.indexRange(query.TimeRange)
+
+			var frames []*data.Frame
+			frames, err = AssetDataQuery(d.cdsClient, qm.NamespaceId, token, qm.Id, startIndex, endIndex, qm.LastValue, qm.IncludeLabels)
+			for _, frame := range frames {
+				response.Frames = append(response.Frames, transformFrame(qm, frame))
+			}
+			return response, err
+		}
+	} else if collection == "assetstatus" {
+		if d.settings.UseCommunity {
+			response.Error = fmt.Errorf("the %s collection is not available for communities", qm.Collection)
+			return response, nil
+		}
+
+		log.DefaultLogger.Debug("Asset status query")
+		frame, err = AssetStatusQuery(d.cdsClient, qm.NamespaceId, token, qm.Query, qm.Id, qm.searchOptions())
+	} else {
+		response.Error = fmt.Errorf("unknown collection %s", qm.Collection)
+		return response, nil
+	}
+
+	if cacheable && err == nil {
+		d.resultCache.set(cacheKey, frame)
 	}
 
 	// add the frames to the response.
+	if err == nil {
+		frame = transformFrame(qm, frame)
+	}
 	response.Frames = append(response.Frames, frame)
 	return response, err
 }
 
-// Handles health checks sent from Grafana to the plugin.
-func (d *CdsDataSource) CheckHealth(_ context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
-	log.DefaultLogger.Error("CHECK HEALTH CALLED")
-	var status = backend.HealthStatusOk
-	var message = "Data source is working"
+// Applies the presentation options of a query to a frame.
+func transformFrame(qm QueryModel, frame *data.Frame) *data.Frame {
+	if qm.BooleansAsNumbers {
+		frame = booleansAsNumbers(frame)
+	}
 
-	// Retrieve token
-	var token string
-	if d.settings.OauthPassThru {
-		return &backend.CheckHealthResult{
-			Status:  status,
-			Message: message,
-		}, nil
-	} else {
-		var err error
-		token, err = GetClientToken(d.cdsClient)
-		if err != nil {
-			log.DefaultLogger.Warn("Error unable to get token health check", err.Error())
-			return &backend.CheckHealthResult{
-				Status:  backend.HealthStatusError,
-				Message: "Unable to retrieve token",
-			}, nil
-		}
+	if len(qm.TargetUoms) > 0 {
+		frame = convertUnits(frame, qm.TargetUoms)
 	}
 
-	// Make a request to test the token
-	var path string
+	return frame
+}
+
+// Reads the data of a stream in the namespace or community between two times.
+func (d *CdsDataSource) streamsDataQuery(qm QueryModel, from time.Time, to time.Time, token string) (*data.Frame, error) {
+	return d.streamsIndexQuery(qm, from.Format(time.RFC3339), to.Format(time.RFC3339), token)
+}
+
+// Reads the data of a stream in the namespace or community between two indexes.
+func (d *CdsDataSource) streamsIndexQuery(qm QueryModel, startIndex string, endIndex string, token string) (*data.Frame, error) {
 	if d.settings.UseCommunity {
-		path = d.cdsClient.resource + "/api/" + d.cdsClient.apiVersion + "/tenants/" + d.cdsClient.tenantId + "/communities/" + d.settings.CommunityId
-	} else {
-		path = d.cdsClient.resource + "/api/" + d.cdsClient.apiVersion + "/tenants/" + d.cdsClient.tenantId + "/namespaces/" + d.settings.NamespaceId
+		log.DefaultLogger.Debug("Community stream data query")
+		return CommunityStreamsDataQuery(d.cdsClient,
+			d.settings.CommunityId,
+			token,
+			qm.Id,
+			startIndex,
+			endIndex,
+			qm.IncludeLabels)
 	}
 
-	body, err := SdsRequest(d.cdsClient, token, path, nil)
-	if err != nil {
-		log.DefaultLogger.Warn("Error test request health check", err.Error())
-		status = backend.HealthStatusError
-		message = "Invalid Configuration"
+	log.DefaultLogger.Debug("Stream data query")
+	return StreamsDataQuery(d.cdsClient,
+		qm.NamespaceId,
+		token,
+		qm.Id,
+		startIndex,
+		endIndex,
+		qm.IncludeLabels)
+}
+
+// Determines the query cache key for a query, and whether the query may use the cache.
+// Only stream data queries over ranges that do not end at the current time are cached.
+func (d *CdsDataSource) queryCacheKey(qm QueryModel, timeRange backend.TimeRange, token string) (string, bool) {
+	if d.resultCache == nil || !strings.EqualFold(qm.Collection, "streams") || qm.Id == "" || qm.hasIndexRange() {
+		return "", false
 	}
 
-	var responseJson CheckHealthResponseBody
+	if isLiveRange(timeRange, time.Now()) {
+		return "", false
+	}
 
-	err = json.Unmarshal(body, &responseJson)
-	if err != nil {
-		log.DefaultLogger.Warn("Error parsing resonse health check", err.Error())
-		status = backend.HealthStatusError
-		message = "Invalid Configuration"
+	mode, scopeId := d.cacheScope(qm)
+	return queryCacheKey(mode, scopeId, qm.cacheStreamId(), timeRange, d.cacheIdentity(token)), true
+}
+
+// Returns the query mode and the namespace or community id that cached results belong to.
+func (d *CdsDataSource) cacheScope(qm QueryModel) (string, string) {
+	if d.settings.UseCommunity {
+		return "community", d.settings.CommunityId
+	}
+	return "namespace", qm.NamespaceId
+}
+
+// Returns the namespace a query reads from. Queries without a namespace read from the
+// namespace of the data source, and other namespaces must be allowed by the settings.
+func (d *CdsDataSource) queryNamespace(qm QueryModel) (string, error) {
+	if qm.NamespaceId == "" {
+		return d.settings.NamespaceId, nil
+	}
+
+	if d.settings.UseCommunity {
+		return "", fmt.Errorf("selecting a namespace is not available for communities")
+	}
+	if !d.settings.AllowsNamespace(qm.NamespaceId) {
+		return "", fmt.Errorf("namespace %s is not allowed by the data source", qm.NamespaceId)
 	}
 
-	return &backend.CheckHealthResult{
-		Status:  status,
-		Message: message,
-	}, nil
+	return qm.NamespaceId, nil
+}
+
+// Returns the identity cached results belong to. Results are only shared between users
+// when a single identity is used for all requests.
+func (d *CdsDataSource) cacheIdentity(token string) string {
+	if d.settings.OauthPassThru {
+		return token
+	}
+	return ""
+}
+
+// Handles health checks sent from Grafana to the plugin. The result lists each step of
+// the check in its details.
+func (d *CdsDataSource) CheckHealth(_ context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+	log.DefaultLogger.Debug("CheckHealth called")
+	return d.healthCheck(req), nil
 }
@@ -0,0 +1,145 @@
+package cds
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aveva/connect-data-services/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestQueryNamespace(t *testing.T) {
+	d := &CdsDataSource{
+		settings: &models.CdsSettings{NamespaceId: "dev", NamespaceIds: []string{"test", "prod"}},
+	}
+	community := &CdsDataSource{
+		settings: &models.CdsSettings{NamespaceId: "dev", UseCommunity: true, CommunityId: communityId},
+	}
+
+	tests := []struct {
+		name        string
+		dataSource  *CdsDataSource
+		namespaceId string
+		expected    string
+		valid       bool
+	}{
+		{name: "default", dataSource: d, namespaceId: "", expected: "dev", valid: true},
+		{name: "data-source-namespace", dataSource: d, namespaceId: "dev", expected: "dev", valid: true},
+		{name: "allowed-namespace", dataSource: d, namespaceId: "prod", expected: "prod", valid: true},
+		{name: "not-allowed", dataSource: d, namespaceId: "other"},
+		{name: "case-sensitive", dataSource: d, namespaceId: "Prod"},
+		{name: "community", dataSource: community, namespaceId: "prod"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			namespaceId, err := test.dataSource.queryNamespace(QueryModel{NamespaceId: test.namespaceId})
+			if (err == nil) != test.valid {
+				t.Fatalf("FAILED: expected valid %v, got %v\n", test.valid, err)
+			}
+			if namespaceId != test.expected {
+				t.Errorf("FAILED: expected %v, got %v\n", test.expected, namespaceId)
+			}
+		})
+	}
+}
+
+func TestEdsQueryBooleansAsNumbers(t *testing.T) {
+	basePath := "/api/v1/tenants/default/namespaces/default"
+	mux := http.NewServeMux()
+	start := time.Date(2022, 6, 4, 0, 0, 0, 0, time.UTC)
+
+	mux.HandleFunc(basePath+"/streams/StreamId1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"TypeId": "StreamType1", "Id": "StreamId1", "Name": "StreamName1"}`))
+	})
+
+	mux.HandleFunc(basePath+"/types/StreamType1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"Id": "StreamType1",
+			"SdsTypeCode": 1,
+			"Properties": [
+				{"Id": "Timestamp", "IsKey": true, "SdsType": {"SdsTypeCode": 16}},
+				{"Id": "Running", "SdsType": {"SdsTypeCode": 3}}
+			]
+		}`))
+	})
+
+	mux.HandleFunc(basePath+"/streams/StreamId1/Data", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"Timestamp": "2022-06-04T00:00:00Z", "Running": true}, {"Timestamp": "2022-06-04T01:00:00Z", "Running": false}]`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	serverUrl, _ := url.Parse(server.URL)
+	client, err := NewEdsClient(serverUrl.Hostname(), serverUrl.Port())
+	if err != nil {
+		t.Fatalf("FAILED: unable to create the Edge Data Store client (%v)\n", err)
+	}
+	dataSource := CdsDataSource{
+		cdsClient: &client,
+		settings:  &models.CdsSettings{Type: models.EdsType, NamespaceId: "default"},
+	}
+
+	tests := []struct {
+		name     string
+		json     string
+		expected interface{}
+	}{
+		{name: "default", json: `{"collection": "streams", "id": "StreamId1"}`, expected: []float64{1, 0}},
+		{name: "disabled", json: `{"collection": "streams", "id": "StreamId1", "booleansAsNumbers": false}`, expected: []bool{true, false}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query := backend.DataQuery{
+				JSON:      []byte(test.json),
+				TimeRange: backend.TimeRange{From: start, To: start.Add(time.Hour)},
+			}
+
+			response, _ := dataSource.query(context.Background(), backend.PluginContext{}, query, "")
+			if response.Error != nil || len(response.Frames) != 1 {
+				t.Fatalf("FAILED: expected one frame, got %v (%v)\n", response.Frames, response.Error)
+			}
+
+			expected := data.NewField("Running", nil, test.expected)
+			field := response.Frames[0].Fields[1]
+			if field.Type() != expected.Type() || field.Len() != 2 || field.At(0) != expected.At(0) || field.At(1) != expected.At(1) {
+				t.Errorf("FAILED: expected %v, got %v\n", expected, field)
+			}
+		})
+	}
+}
+
+func TestIndexRange(t *testing.T) {
+	timeRange := backend.TimeRange{From: time.Date(2022, 6, 4, 0, 0, 0, 0, time.UTC), To: time.Date(2022, 6, 5, 0, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name          string
+		qm            QueryModel
+		expectedStart string
+		expectedEnd   string
+	}{
+		{name: "time-range", qm: QueryModel{}, expectedStart: "2022-06-04T00:00:00Z", expectedEnd: "2022-06-05T00:00:00Z"},
+		{name: "index-range", qm: QueryModel{StartIndex: "7|1", EndIndex: "7|20"}, expectedStart: "7|1", expectedEnd: "7|20"},
+		{name: "start-only", qm: QueryModel{StartIndex: "2022-06-04T12:00:00Z"}, expectedStart: "2022-06-04T12:00:00Z", expectedEnd: "2022-06-05T00:00:00Z"},
+		{name: "end-only", qm: QueryModel{EndIndex: "$__to|0"}, expectedStart: "2022-06-04T00:00:00Z", expectedEnd: "2022-06-05T00:00:00Z|0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			startIndex, endIndex := test.qm.indexRange(timeRange)
+			if startIndex != test.expectedStart || endIndex != test.expectedEnd {
+				t.Errorf("FAILED: expected %s to %s, got %s to %s\n", test.expectedStart, test.expectedEnd, startIndex, endIndex)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package dataviews
+
+type DataView struct {
+	Id                string             `json:"Id"`
+	Name              string             `json:"Name"`
+	Description       string             `json:"Description"`
+	IndexField        DataViewIndexField `json:"IndexField"`
+	IndexTypeCode     string             `json:"IndexTypeCode"`
+	DefaultStartIndex string             `json:"DefaultStartIndex"`
+	DefaultEndIndex   string             `json:"DefaultEndIndex"`
+	DefaultInterval   string             `json:"DefaultInterval"`
+}
@@ -0,0 +1,5 @@
+package dataviews
+
+type DataViewIndexField struct {
+	Label string `json:"Label"`
+}
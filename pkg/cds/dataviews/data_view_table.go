@@ -0,0 +1,16 @@
+package dataviews
+
+import (
+	"encoding/json"
+)
+
+// Data view data in the table form, with the name and SdsTypeCode of each column.
+type DataViewTable struct {
+	Columns []DataViewColumn    `json:"Columns"`
+	Rows    [][]json.RawMessage `json:"Rows"`
+}
+
+type DataViewColumn struct {
+	Name string `json:"Name"`
+	Type string `json:"Type"`
+}
@@ -0,0 +1,102 @@
+package cds
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// Returned when the OAuth identity forwarded by Grafana has expired. Grafana refreshes the
+// tokens of signed in users when it can, so an expired token needs a new sign in.
+var errExpiredIdentity = errors.New("the OAuth token of the signed in user has expired, sign out and sign in to Grafana again")
+
+// Returned when Grafana did not forward an OAuth identity, as for users signed in without
+// CONNECT data services, and for alerting when the client credentials fallback is disabled.
+var errMissingIdentity = errors.New("unable to retrieve token, no OAuth identity was forwarded; sign in to Grafana with " +
+	"CONNECT data services, or enable the client credentials fallback to use alerting and other background requests")
+
+// Determines whether a request is made without a signed in user, as for alert rules. Only
+// these requests may fall back to the client credentials, so that users signed in without
+// an OAuth identity never get the data access of the client. Request headers are not
+// used, since they can be set by the user making the request.
+func isBackgroundRequest(pCtx backend.PluginContext) bool {
+	return pCtx.User == nil
+}
+
+// Returns the token used for the requests of a query or health check. With OAuth
+// passthrough, the identity forwarded by Grafana is used, falling back to the client
+// credentials for background requests when no identity is forwarded and the fallback
+// is enabled.
+func (d *CdsDataSource) requestToken(getHTTPHeader func(string) string, background bool) (string, error) {
+	if !d.settings.OauthPassThru {
+		return GetClientToken(d.cdsClient)
+	}
+
+	token := getHTTPHeader(backend.OAuthIdentityTokenHeaderName)
+	idToken := getHTTPHeader(backend.OAuthIdentityIDTokenHeaderName)
+	if token == "" {
+		if d.settings.ClientCredentialsFallback && background {
+			log.DefaultLogger.Debug("No OAuth identity forwarded, using client credentials")
+			return GetClientToken(d.cdsClient)
+		}
+		return "", errMissingIdentity
+	}
+
+	err := checkForwardedIdentity(token, idToken, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Checks that a forwarded identity has not expired. The expiration is read from the access
+// token, or from the ID token when the access token is not a JWT. Identities without a
+// readable expiration are left for CONNECT data services to validate.
+func checkForwardedIdentity(token string, idToken string, now time.Time) error {
+	expiration, ok := tokenExpiration(strings.TrimSpace(strings.TrimPrefix(token, "Bearer ")))
+	if !ok {
+		expiration, ok = tokenExpiration(idToken)
+	}
+
+	if ok && !expiration.After(now) {
+		log.DefaultLogger.Warn("Forwarded OAuth token expired", "expiration", expiration)
+		return errExpiredIdentity
+	}
+
+	return nil
+}
+
+// Reads the exp claim of a JWT. The signature is not verified, since the token is only
+// inspected to report expiration and is validated by CONNECT data services.
+func tokenExpiration(jwt string) (time.Time, bool) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		Exp *json.Number `json:"exp"`
+	}
+	err = json.Unmarshal(payload, &claims)
+	if err != nil || claims.Exp == nil {
+		return time.Time{}, false
+	}
+
+	exp, err := claims.Exp.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(exp), 0), true
+}
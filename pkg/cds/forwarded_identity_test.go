@@ -0,0 +1,101 @@
+package cds
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aveva/connect-data-services/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// Builds an unsigned JWT expiring at a time.
+func testJwt(expiration time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"user","exp":` + strconv.FormatInt(expiration.Unix(), 10) + `}`))
+	return "eyJhbGciOiJSUzI1NiJ9." + payload + ".signature"
+}
+
+func TestCheckForwardedIdentity(t *testing.T) {
+	now := time.Date(2022, 6, 5, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		token    string
+		idToken  string
+		expected error
+	}{
+		{name: "valid", token: "Bearer " + testJwt(now.Add(time.Hour)), expected: nil},
+		{name: "expired", token: "Bearer " + testJwt(now.Add(-time.Minute)), expected: errExpiredIdentity},
+		{name: "opaque-token-expired-id-token", token: "Bearer opaque", idToken: testJwt(now.Add(-time.Minute)), expected: errExpiredIdentity},
+		{name: "opaque-token-valid-id-token", token: "Bearer opaque", idToken: testJwt(now.Add(time.Hour)), expected: nil},
+		{name: "opaque-tokens", token: "Bearer opaque", idToken: "", expected: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkForwardedIdentity(test.token, test.idToken, now)
+			if !errors.Is(err, test.expected) {
+				t.Errorf("FAILED: expected %v, got %v\n", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestRequestToken(t *testing.T) {
+	forwarded := "Bearer " + testJwt(time.Now().Add(time.Hour))
+	headers := func(token string) func(string) string {
+		return func(key string) string {
+			if key == backend.OAuthIdentityTokenHeaderName {
+				return token
+			}
+			return ""
+		}
+	}
+
+	// a cached client token, so that the fallback does not request one
+	client := NewCdsClient("http://localhost", apiVersion, tenantId, "client", "secret")
+	client.token = "client-token"
+	client.tokenExpiration = time.Now().Add(time.Hour).Unix()
+
+	passThru := &CdsDataSource{cdsClient: &client, settings: &models.CdsSettings{OauthPassThru: true}}
+	fallback := &CdsDataSource{cdsClient: &client, settings: &models.CdsSettings{OauthPassThru: true, ClientCredentialsFallback: true}}
+
+	token, err := passThru.requestToken(headers(forwarded), false)
+	if err != nil || token != forwarded {
+		t.Errorf("FAILED: expected forwarded token, got %v (%v)\n", token, err)
+	}
+
+	_, err = passThru.requestToken(headers(""), true)
+	if !errors.Is(err, errMissingIdentity) {
+		t.Errorf("FAILED: expected %v, got %v\n", errMissingIdentity, err)
+	}
+
+	token, err = fallback.requestToken(headers(""), true)
+	if err != nil || token != "Bearer client-token" {
+		t.Errorf("FAILED: expected client token, got %v (%v)\n", token, err)
+	}
+
+	// a signed in user without an identity does not get the access of the client
+	_, err = fallback.requestToken(headers(""), false)
+	if !errors.Is(err, errMissingIdentity) {
+		t.Errorf("FAILED: expected %v, got %v\n", errMissingIdentity, err)
+	}
+
+	_, err = fallback.requestToken(headers("Bearer "+testJwt(time.Now().Add(-time.Hour))), true)
+	if !errors.Is(err, errExpiredIdentity) {
+		t.Errorf("FAILED: expected %v, got %v\n", errExpiredIdentity, err)
+	}
+}
+
+func TestIsBackgroundRequest(t *testing.T) {
+	user := backend.PluginContext{User: &backend.User{Login: "user"}}
+
+	if !isBackgroundRequest(backend.PluginContext{}) {
+		t.Errorf("FAILED: expected a request without a user to be a background request\n")
+	}
+	if isBackgroundRequest(user) {
+		t.Errorf("FAILED: expected a signed in user not to be a background request\n")
+	}
+}
@@ -0,0 +1,118 @@
+package cds
+
+import (
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Returns a copy of a frame with boolean fields converted to 1 and 0, so that state
+// timelines and alerts can use them. The original frame is not modified, since it may
+// be shared with the query caches.
+func booleansAsNumbers(frame *data.Frame) *data.Frame {
+	converted := *frame
+	converted.Fields = make([]*data.Field, len(frame.Fields))
+
+	for i, field := range frame.Fields {
+		switch field.Type() {
+		case data.FieldTypeBool:
+			values := make([]float64, field.Len())
+			for row := range values {
+				if field.At(row).(bool) {
+					values[row] = 1
+				}
+			}
+			converted.Fields[i] = copyFieldWithValues(field, values)
+		case data.FieldTypeNullableBool:
+			values := make([]*float64, field.Len())
+			for row := range values {
+				if value := field.At(row).(*bool); value != nil {
+					number := 0.0
+					if *value {
+						number = 1
+					}
+					values[row] = &number
+				}
+			}
+			converted.Fields[i] = copyFieldWithValues(field, values)
+		default:
+			converted.Fields[i] = field
+		}
+	}
+
+	return &converted
+}
+
+// Returns a copy of a frame with numeric fields converted to the target unit of measure
+// of the same quantity, such as degree Fahrenheit for fields in degree Celsius. The source
+// unit of measure is the SDS unit of measure kept on the field. Fields without one, or
+// without a target of their quantity, are unchanged.
+func convertUnits(frame *data.Frame, targetUoms []string) *data.Frame {
+	converted := *frame
+	converted.Fields = make([]*data.Field, len(frame.Fields))
+
+	for i, field := range frame.Fields {
+		converted.Fields[i] = field
+		source := fieldUom(field)
+		if !field.Type().Numeric() || source == "" {
+			continue
+		}
+
+		for _, target := range targetUoms {
+			target = strings.TrimSpace(target)
+			if strings.EqualFold(source, target) {
+				break
+			}
+
+			convert, ok := uomConverter(source, target)
+			if !ok {
+				continue
+			}
+
+			converted.Fields[i] = convertFieldValues(field, convert, target)
+			break
+		}
+	}
+
+	return &converted
+}
+
+// Creates a float64 field with the converted values of a numeric field and a new unit of
+// measure.
+func convertFieldValues(field *data.Field, convert func(float64) float64, uom string) *data.Field {
+	var copied *data.Field
+	if field.Nullable() {
+		values := make([]*float64, field.Len())
+		for row := range values {
+			if value, err := field.NullableFloatAt(row); err == nil && value != nil {
+				number := convert(*value)
+				values[row] = &number
+			}
+		}
+		copied = copyFieldWithValues(field, values)
+	} else {
+		values := make([]float64, field.Len())
+		for row := range values {
+			value, _ := field.FloatAt(row)
+			values[row] = convert(value)
+		}
+		copied = copyFieldWithValues(field, values)
+	}
+
+	config := *field.Config
+	config.Unit = grafanaUnit(uom)
+	config.Custom = map[string]interface{}{}
+	for key, value := range field.Config.Custom {
+		config.Custom[key] = value
+	}
+	config.Custom[uomConfigKey] = uom
+	copied.Config = &config
+	return copied
+}
+
+// Creates a field with the name, labels and config of another field and new values.
+func copyFieldWithValues(field *data.Field, values interface{}) *data.Field {
+	copied := data.NewField(field.Name, field.Labels, values)
+	copied.Config = field.Config
+	return copied
+}
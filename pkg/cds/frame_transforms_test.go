@@ -0,0 +1,89 @@
+package cds
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestBooleansAsNumbers(t *testing.T) {
+	off := false
+	frame := data.NewFrame("test",
+		data.NewField("Open", nil, []bool{true, false}),
+		data.NewField("Closed", nil, []*bool{&off, nil}),
+		data.NewField("Value", nil, []float64{1.5, 2.5}),
+	)
+	frame.Fields[1].Config = &data.FieldConfig{Unit: "bool"}
+
+	one, zero := 1.0, 0.0
+	expected := data.NewFrame("test",
+		data.NewField("Open", nil, []float64{one, zero}),
+		data.NewField("Closed", nil, []*float64{&zero, nil}),
+		data.NewField("Value", nil, []float64{1.5, 2.5}),
+	)
+	expected.Fields[1].Config = &data.FieldConfig{Unit: "bool"}
+
+	converted := booleansAsNumbers(frame)
+	if !reflect.DeepEqual(converted, expected) {
+		t.Errorf("FAILED: expected %v, got %v\n", expected, converted)
+	}
+
+	// the original frame is left unchanged
+	if frame.Fields[0].Type() != data.FieldTypeBool || frame.Fields[1].Type() != data.FieldTypeNullableBool {
+		t.Errorf("FAILED: expected the original frame to be unchanged, got %v\n", frame)
+	}
+}
+
+func TestConvertUnits(t *testing.T) {
+	boiling := 100.0
+	frame := data.NewFrame("test",
+		data.NewField("Inlet", nil, []float32{0, 100}),
+		data.NewField("Outlet", nil, []*float64{&boiling, nil}),
+		data.NewField("Pressure", nil, []float64{1}),
+		data.NewField("Count", nil, []int32{5}),
+	)
+	celsius := map[string]interface{}{uomConfigKey: "degree Celsius"}
+	frame.Fields[0].Config = &data.FieldConfig{Unit: "celsius", Description: "Inlet temperature", Custom: celsius}
+	frame.Fields[1].Config = &data.FieldConfig{Unit: "suffix:degree Celsius", Custom: celsius}
+	frame.Fields[2].Config = &data.FieldConfig{Unit: "pressurebar", Custom: map[string]interface{}{uomConfigKey: "bar"}}
+
+	fahrenheit := 212.0
+	expected := data.NewFrame("test",
+		data.NewField("Inlet", nil, []float64{32, 212}),
+		data.NewField("Outlet", nil, []*float64{&fahrenheit, nil}),
+		data.NewField("Pressure", nil, []float64{1}),
+		data.NewField("Count", nil, []int32{5}),
+	)
+	fahrenheitUom := map[string]interface{}{uomConfigKey: "degree Fahrenheit"}
+	expected.Fields[0].Config = &data.FieldConfig{Unit: "fahrenheit", Description: "Inlet temperature", Custom: fahrenheitUom}
+	expected.Fields[1].Config = &data.FieldConfig{Unit: "fahrenheit", Custom: fahrenheitUom}
+	expected.Fields[2].Config = &data.FieldConfig{Unit: "pressurebar", Custom: map[string]interface{}{uomConfigKey: "bar"}}
+
+	converted := convertUnits(frame, []string{"meter", "degree Fahrenheit", "bar"})
+	for i, field := range converted.Fields {
+		for row := 0; row < field.Len(); row++ {
+			got, _ := field.NullableFloatAt(row)
+			want, _ := expected.Fields[i].NullableFloatAt(row)
+			if (got == nil) != (want == nil) || (got != nil && math.Abs(*got-*want) > 1e-9) {
+				t.Errorf("FAILED: expected %v, got %v for %s\n", want, got, field.Name)
+			}
+		}
+		if field.Type() != expected.Fields[i].Type() || !reflect.DeepEqual(field.Config, expected.Fields[i].Config) {
+			t.Errorf("FAILED: expected %v, got %v\n", expected.Fields[i], field)
+		}
+	}
+
+	// the original frame is left unchanged
+	if frame.Fields[0].Config.Unit != "celsius" || fieldUom(frame.Fields[0]) != "degree Celsius" || frame.Fields[0].At(1).(float32) != 100 {
+		t.Errorf("FAILED: expected the original frame to be unchanged, got %v\n", frame)
+	}
+
+	// fields without an SDS unit of measure are not converted from their Grafana unit
+	unknown := data.NewFrame("test", data.NewField("Outlet", nil, []float64{100}))
+	unknown.Fields[0].Config = &data.FieldConfig{Unit: "celsius"}
+	if converted := convertUnits(unknown, []string{"degree Fahrenheit"}); converted.Fields[0].At(0) != 100.0 {
+		t.Errorf("FAILED: expected the field without a unit of measure to be unchanged, got %v\n", converted.Fields[0].At(0))
+	}
+}
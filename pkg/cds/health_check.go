@@ -0,0 +1,234 @@
+package cds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/aveva/connect-data-services/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Status of a step of the health check.
+const (
+	healthStepOk      = "ok"
+	healthStepError   = "error"
+	healthStepSkipped = "skipped"
+)
+
+// Result of a step of the health check, returned in the details of the result so that
+// misconfiguration can be diagnosed without the plugin logs.
+type healthCheckStep struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Runs the steps of a health check in order. Once a step fails, the following steps are
+// skipped since they depend on it.
+type healthCheck struct {
+	Steps  []healthCheckStep `json:"steps"`
+	failed *healthCheckStep
+}
+
+// Runs a step, which returns a message describing its result.
+func (h *healthCheck) run(name string, step func() (string, error)) {
+	if h.failed != nil {
+		h.skip(name, "skipped after "+h.failed.Name+" failed")
+		return
+	}
+
+	message, err := step()
+	if err != nil {
+		log.DefaultLogger.Warn("Health check failed", "step", name, "error", err.Error())
+		failed := healthCheckStep{Name: name, Status: healthStepError, Message: err.Error()}
+		h.Steps = append(h.Steps, failed)
+		h.failed = &failed
+		return
+	}
+
+	h.Steps = append(h.Steps, healthCheckStep{Name: name, Status: healthStepOk, Message: message})
+}
+
+// Records a step that does not apply to the data source.
+func (h *healthCheck) skip(name string, reason string) {
+	h.Steps = append(h.Steps, healthCheckStep{Name: name, Status: healthStepSkipped, Message: reason})
+}
+
+// Returns the result of the health check, with the steps as details.
+func (h *healthCheck) result() *backend.CheckHealthResult {
+	result := &backend.CheckHealthResult{
+		Status:  backend.HealthStatusOk,
+		Message: "Data source is working",
+	}
+	if h.failed != nil {
+		result.Status = backend.HealthStatusError
+		result.Message = fmt.Sprintf("%s failed: %s", h.failed.Name, h.failed.Message)
+	}
+
+	details, err := json.Marshal(h)
+	if err != nil {
+		log.DefaultLogger.Warn("Error formatting health check details", err.Error())
+		return result
+	}
+	result.JSONDetails = details
+
+	return result
+}
+
+// Checks the configuration of the data source step by step: the settings, the identity
+// discovery document, the token, the tenant, the namespaces or community, and a sample
+// stream search.
+func (d *CdsDataSource) healthCheck(req *backend.CheckHealthRequest) *backend.CheckHealthResult {
+	check := &healthCheck{}
+	eds := d.settings.IsEds()
+
+	check.run("settings", func() (string, error) {
+		return "settings are complete", validateSettings(d.settings)
+	})
+
+	clientCredentials := !d.settings.OauthPassThru || d.settings.ClientCredentialsFallback
+	if eds || !clientCredentials {
+		check.skip("discovery", "no client credentials are used")
+	} else {
+		check.run("discovery", func() (string, error) {
+			tokenEndpoint, err := discoverTokenEndpoint(d.cdsClient)
+			return "token endpoint is " + tokenEndpoint, err
+		})
+	}
+
+	var token string
+	if eds {
+		check.skip("token", "Edge Data Store is not authenticated")
+	} else {
+		check.run("token", func() (string, error) {
+			var err error
+			// the health check is made by the admin saving the data source, so it tests the
+			// client credentials fallback when it is enabled and no identity is forwarded
+			token, err = d.requestToken(req.GetHTTPHeader, true)
+			if d.settings.OauthPassThru && req.GetHTTPHeader(backend.OAuthIdentityTokenHeaderName) != "" {
+				return "forwarded OAuth token is valid", err
+			}
+			return "token acquired for client " + d.settings.ClientId, err
+		})
+	}
+
+	basePath := d.cdsClient.resource + "/api/" + d.cdsClient.apiVersion + "/tenants/" + url.QueryEscape(d.cdsClient.tenantId)
+	if eds {
+		check.skip("tenant", "Edge Data Store has a single default tenant")
+	} else {
+		check.run("tenant", func() (string, error) {
+			return "tenant " + d.settings.TenantId + " is reachable", checkResource(d.cdsClient, token, basePath)
+		})
+	}
+
+	if d.settings.UseCommunity {
+		check.run("community", func() (string, error) {
+			path := basePath + "/communities/" + url.QueryEscape(d.settings.CommunityId)
+			return "community " + d.settings.CommunityId + " is accessible", checkResource(d.cdsClient, token, path)
+		})
+	} else {
+		for _, namespaceId := range append([]string{d.settings.NamespaceId}, d.settings.NamespaceIds...) {
+			check.run("namespace "+namespaceId, func() (string, error) {
+				path := basePath + "/namespaces/" + url.QueryEscape(namespaceId)
+				return "namespace " + namespaceId + " is accessible", checkResource(d.cdsClient, token, path)
+			})
+		}
+	}
+
+	check.run("stream query", func() (string, error) {
+		var frame *data.Frame
+		var err error
+		if d.settings.UseCommunity {
+			frame, err = CommunityStreamsQuery(d.cdsClient, d.settings.CommunityId, token, "", SearchOptions{Count: 1})
+		} else {
+			frame, err = StreamsQuery(d.cdsClient, d.settings.NamespaceId, token, "", SearchOptions{Count: 1})
+		}
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("stream search returned %d streams", frame.Rows()), nil
+	})
+
+	return check.result()
+}
+
+// Checks that the settings required to connect are present and well formed.
+func validateSettings(settings *models.CdsSettings) error {
+	if settings.IsEds() {
+		if settings.EdsHost == "" {
+			return fmt.Errorf("the Edge Data Store host is required")
+		}
+		if _, err := edsResource(settings.EdsHost, settings.EdsPort); err != nil {
+			return err
+		}
+		if settings.NamespaceId == "" {
+			return fmt.Errorf("the namespace is required")
+		}
+		return nil
+	}
+
+	resource, err := url.Parse(settings.Resource)
+	if err != nil || (resource.Scheme != "https" && resource.Scheme != "http") || resource.Host == "" {
+		return fmt.Errorf("the URL %s is not a valid http or https URL", settings.Resource)
+	}
+	if resource.Path != "" && resource.Path != "/" {
+		return fmt.Errorf("the URL %s should not contain a path", settings.Resource)
+	}
+
+	if settings.ApiVersion == "" {
+		return fmt.Errorf("the API version is required")
+	}
+	if settings.TenantId == "" {
+		return fmt.Errorf("the tenant id is required")
+	}
+	if settings.UseCommunity && settings.CommunityId == "" {
+		return fmt.Errorf("the community id is required when using community data")
+	}
+	if !settings.UseCommunity && settings.NamespaceId == "" {
+		return fmt.Errorf("the namespace id is required")
+	}
+
+	if !settings.OauthPassThru || settings.ClientCredentialsFallback {
+		if settings.ClientId == "" {
+			return fmt.Errorf("the client id is required when not using the OAuth token or when falling back to client credentials")
+		}
+
+		switch settings.ClientAuthMethod {
+		case "", ClientSecretPost, ClientSecretBasic:
+			if settings.Secrets == nil || settings.Secrets.ClientSecret == "" {
+				return fmt.Errorf("the client secret is required when not using the OAuth token or when falling back to client credentials")
+			}
+		case PrivateKeyJwt:
+			if settings.Secrets == nil || settings.Secrets.ClientCertificate == "" {
+				return fmt.Errorf("the client certificate is required for %s client authentication", PrivateKeyJwt)
+			}
+			if _, _, err := parseClientCertificate(settings.Secrets.ClientCertificate); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown client authentication method %s", settings.ClientAuthMethod)
+		}
+	}
+
+	return nil
+}
+
+// Checks that a resource, such as a tenant or namespace, can be read with a token.
+func checkResource(d *CdsClient, token string, path string) error {
+	body, err := SdsRequest(d, token, path, nil)
+	if err != nil {
+		return err
+	}
+
+	var responseJson CheckHealthResponseBody
+	err = json.Unmarshal(body, &responseJson)
+	if err != nil {
+		return fmt.Errorf("unexpected response from %s: %w", path, err)
+	}
+
+	return nil
+}
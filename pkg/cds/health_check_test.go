@@ -0,0 +1,127 @@
+package cds
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aveva/connect-data-services/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestCheckHealth(t *testing.T) {
+	basePath := "/api/" + apiVersion + "/tenants/" + tenantId
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/identity/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"token_endpoint": "http://` + r.Host + `/identity/connect/token"}`))
+	})
+
+	mux.HandleFunc("/identity/connect/token", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"access_token": "token", "expires_in": 3600}`))
+	})
+
+	mux.HandleFunc(basePath, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"Id": "default"}`))
+	})
+
+	mux.HandleFunc(basePath+"/namespaces/"+namespaceId, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"Id": "default"}`))
+	})
+
+	mux.HandleFunc(basePath+"/namespaces/"+namespaceId+"/streams", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"Id": "StreamId1", "Name": "StreamName1", "TypeId": "StreamType1"}]`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	settings := func(namespaceIds []string, clientSecret string) *models.CdsSettings {
+		return &models.CdsSettings{
+			Resource:     server.URL,
+			ApiVersion:   apiVersion,
+			TenantId:     tenantId,
+			NamespaceId:  namespaceId,
+			NamespaceIds: namespaceIds,
+			ClientId:     "client",
+			Secrets:      &models.SecretCdsSettings{ClientSecret: clientSecret},
+		}
+	}
+
+	oauth := func(fallback bool) *models.CdsSettings {
+		oauthSettings := settings(nil, "secret")
+		oauthSettings.OauthPassThru = true
+		oauthSettings.ClientCredentialsFallback = fallback
+		return oauthSettings
+	}
+
+	tests := []struct {
+		name     string
+		settings *models.CdsSettings
+		status   backend.HealthStatus
+		steps    []string
+	}{
+		{
+			name:     "working",
+			settings: settings(nil, "secret"),
+			status:   backend.HealthStatusOk,
+			steps:    []string{"ok", "ok", "ok", "ok", "ok", "ok"},
+		},
+		{
+			name:     "missing-secret",
+			settings: settings(nil, ""),
+			status:   backend.HealthStatusError,
+			steps:    []string{"error", "skipped", "skipped", "skipped", "skipped", "skipped"},
+		},
+		{
+			name:     "oauth-client-fallback",
+			settings: oauth(true),
+			status:   backend.HealthStatusOk,
+			steps:    []string{"ok", "ok", "ok", "ok", "ok", "ok"},
+		},
+		{
+			name:     "oauth-without-identity",
+			settings: oauth(false),
+			status:   backend.HealthStatusError,
+			steps:    []string{"ok", "skipped", "error", "skipped", "skipped", "skipped"},
+		},
+		{
+			name:     "inaccessible-namespace",
+			settings: settings([]string{"prod"}, "secret"),
+			status:   backend.HealthStatusError,
+			steps:    []string{"ok", "ok", "ok", "ok", "ok", "error", "skipped"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := NewCdsClient(server.URL, apiVersion, tenantId, "client", test.settings.Secrets.ClientSecret)
+			d := &CdsDataSource{cdsClient: &client, settings: test.settings}
+
+			// the health check is made by a signed in admin, here without an OAuth identity
+			result := d.healthCheck(&backend.CheckHealthRequest{
+				PluginContext: backend.PluginContext{User: &backend.User{Login: "admin"}},
+			})
+			if result.Status != test.status {
+				t.Errorf("FAILED: expected %v, got %v (%v)\n", test.status, result.Status, result.Message)
+			}
+
+			var details healthCheck
+			err := json.Unmarshal(result.JSONDetails, &details)
+			steps := make([]string, len(details.Steps))
+			for i, step := range details.Steps {
+				steps[i] = step.Status
+			}
+			if err != nil || !reflect.DeepEqual(steps, test.steps) {
+				t.Errorf("FAILED: expected %v, got %v (%v)\n", test.steps, details.Steps, err)
+			}
+		})
+	}
+}
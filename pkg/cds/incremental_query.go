@@ -0,0 +1,243 @@
+package cds
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Streams that have not been queried within this time are evicted from the incremental cache.
+const incrementalIdleTimeout = 15 * time.Minute
+
+// Previously fetched data of a stream, kept so that rolling dashboards only request new events.
+type incrementalEntry struct {
+	frame *data.Frame
+	from  time.Time
+}
+
+// Reads the data of a stream for a range ending at the current time. When the stream was
+// fetched before and the cached data covers the start of the range, only data after the
+// last cached index is requested. The cached data before the range is trimmed and the new
+// data is appended. Data written behind the last cached index is not picked up until the
+// entry is evicted or the range start moves before the cached range.
+func (d *CdsDataSource) incrementalStreamsDataQuery(qm QueryModel, timeRange backend.TimeRange, token string) (*data.Frame, error) {
+	mode, scopeId := d.cacheScope(qm)
+	key := incrementalCacheKey(mode, scopeId, qm.cacheStreamId(), timeRange, d.cacheIdentity(token))
+
+	entry, ok := d.incrementalCache.get(key)
+	if ok && !entry.from.After(timeRange.From) {
+		if lastIndex, found := lastFrameIndex(entry.frame); found {
+			update, err := d.streamsDataQuery(qm, lastIndex, timeRange.To, token)
+			if err != nil {
+				return nil, err
+			}
+
+			merged, err := mergeIncrementalFrames(entry.frame, update, timeRange.From, lastIndex)
+			if err == nil {
+				log.DefaultLogger.Debug("Incremental stream data query", "stream", qm.Id, "from", lastIndex)
+				d.incrementalCache.set(key, &incrementalEntry{frame: merged, from: timeRange.From})
+				return merged, nil
+			}
+
+			log.DefaultLogger.Warn("Unable to merge incremental data", "stream", qm.Id, "error", err.Error())
+		}
+	}
+
+	frame, err := d.streamsDataQuery(qm, timeRange.From, timeRange.To, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, found := lastFrameIndex(frame); found {
+		d.incrementalCache.set(key, &incrementalEntry{frame: frame, from: timeRange.From})
+	}
+
+	return frame, nil
+}
+
+// Builds the incremental cache key of a stream and the duration of the queried time range.
+// The key does not depend on the start of the range, so that rolling ranges share an entry,
+// while panels with ranges of different lengths keep separate entries instead of trimming
+// each other's data.
+func incrementalCacheKey(mode string, scopeId string, streamId string, timeRange backend.TimeRange, token string) string {
+	duration := timeRange.To.Sub(timeRange.From).Round(time.Second)
+	parts := []string{mode, scopeId, streamId, duration.String()}
+	if token != "" {
+		parts = append(parts, hashToken(token))
+	}
+
+	return strings.Join(parts, "|")
+}
+
+// Returns the index of the time field used as the key of a frame.
+func timeFieldIndex(frame *data.Frame) int {
+	for i, field := range frame.Fields {
+		if field.Type() == data.FieldTypeTime {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Returns the last time index of a frame, if the frame is time indexed and has rows.
+func lastFrameIndex(frame *data.Frame) (time.Time, bool) {
+	if frame.Meta != nil && frame.Meta.Type == data.FrameTypeTable {
+		return time.Time{}, false
+	}
+
+	index := timeFieldIndex(frame)
+	if index < 0 || frame.Fields[index].Len() == 0 {
+		return time.Time{}, false
+	}
+
+	field := frame.Fields[index]
+	return field.At(field.Len() - 1).(time.Time), true
+}
+
+// Creates a new frame from the cached rows between from and fetchStart followed by all
+// rows of the update. The cached frame is not modified, since it may still be in use.
+func mergeIncrementalFrames(cached *data.Frame, update *data.Frame, from time.Time, fetchStart time.Time) (*data.Frame, error) {
+	if len(cached.Fields) != len(update.Fields) {
+		return nil, fmt.Errorf("field count changed from %d to %d", len(cached.Fields), len(update.Fields))
+	}
+
+	for i := range cached.Fields {
+		if cached.Fields[i].Name != update.Fields[i].Name || cached.Fields[i].Type() != update.Fields[i].Type() {
+			return nil, fmt.Errorf("field %s changed", cached.Fields[i].Name)
+		}
+	}
+
+	index := timeFieldIndex(cached)
+	if index < 0 {
+		return nil, fmt.Errorf("frame %s has no time field", cached.Name)
+	}
+
+	// the cached data is ordered by index, so the kept rows are one range of each field
+	times := cached.Fields[index]
+	first := sort.Search(times.Len(), func(row int) bool {
+		return !times.At(row).(time.Time).Before(from)
+	})
+	last := sort.Search(times.Len(), func(row int) bool {
+		return !times.At(row).(time.Time).Before(fetchStart)
+	})
+	last = max(first, last)
+
+	merged := data.NewFrame(cached.Name)
+	for i, field := range cached.Fields {
+		mergedField, err := mergeFields(field, update.Fields[i], first, last)
+		if err != nil {
+			return nil, err
+		}
+		merged.Fields = append(merged.Fields, mergedField)
+	}
+
+	// the metadata of the update describes the query that was executed for this request,
+	// while the notices of the cached data still apply to the rows that are kept
+	if update.Meta != nil || (cached.Meta != nil && len(cached.Meta.Notices) > 0) {
+		meta := data.FrameMeta{}
+		if update.Meta != nil {
+			meta = *update.Meta
+		}
+		meta.Notices = nil
+		if cached.Meta != nil {
+			meta.Notices = append(meta.Notices, cached.Meta.Notices...)
+		}
+		if update.Meta != nil {
+			for _, notice := range update.Meta.Notices {
+				if !slices.Contains(meta.Notices, notice) {
+					meta.Notices = append(meta.Notices, notice)
+				}
+			}
+		}
+		merged.Meta = &meta
+	}
+
+	return merged, nil
+}
+
+// Creates a field from the cached values between first and last followed by all values of
+// the update, copying the typed values of each field instead of boxing every row.
+func mergeFields(cached *data.Field, update *data.Field, first int, last int) (*data.Field, error) {
+	switch cached.Type() {
+	case data.FieldTypeTime:
+		return mergeFieldValues[time.Time](cached, update, first, last), nil
+	case data.FieldTypeNullableTime:
+		return mergeFieldValues[*time.Time](cached, update, first, last), nil
+	case data.FieldTypeBool:
+		return mergeFieldValues[bool](cached, update, first, last), nil
+	case data.FieldTypeNullableBool:
+		return mergeFieldValues[*bool](cached, update, first, last), nil
+	case data.FieldTypeInt8:
+		return mergeFieldValues[int8](cached, update, first, last), nil
+	case data.FieldTypeNullableInt8:
+		return mergeFieldValues[*int8](cached, update, first, last), nil
+	case data.FieldTypeUint8:
+		return mergeFieldValues[uint8](cached, update, first, last), nil
+	case data.FieldTypeNullableUint8:
+		return mergeFieldValues[*uint8](cached, update, first, last), nil
+	case data.FieldTypeInt16:
+		return mergeFieldValues[int16](cached, update, first, last), nil
+	case data.FieldTypeNullableInt16:
+		return mergeFieldValues[*int16](cached, update, first, last), nil
+	case data.FieldTypeUint16:
+		return mergeFieldValues[uint16](cached, update, first, last), nil
+	case data.FieldTypeNullableUint16:
+		return mergeFieldValues[*uint16](cached, update, first, last), nil
+	case data.FieldTypeInt32:
+		return mergeFieldValues[int32](cached, update, first, last), nil
+	case data.FieldTypeNullableInt32:
+		return mergeFieldValues[*int32](cached, update, first, last), nil
+	case data.FieldTypeUint32:
+		return mergeFieldValues[uint32](cached, update, first, last), nil
+	case data.FieldTypeNullableUint32:
+		return mergeFieldValues[*uint32](cached, update, first, last), nil
+	case data.FieldTypeInt64:
+		return mergeFieldValues[int64](cached, update, first, last), nil
+	case data.FieldTypeNullableInt64:
+		return mergeFieldValues[*int64](cached, update, first, last), nil
+	case data.FieldTypeUint64:
+		return mergeFieldValues[uint64](cached, update, first, last), nil
+	case data.FieldTypeNullableUint64:
+		return mergeFieldValues[*uint64](cached, update, first, last), nil
+	case data.FieldTypeFloat32:
+		return mergeFieldValues[float32](cached, update, first, last), nil
+	case data.FieldTypeNullableFloat32:
+		return mergeFieldValues[*float32](cached, update, first, last), nil
+	case data.FieldTypeFloat64:
+		return mergeFieldValues[float64](cached, update, first, last), nil
+	case data.FieldTypeNullableFloat64:
+		return mergeFieldValues[*float64](cached, update, first, last), nil
+	case data.FieldTypeString:
+		return mergeFieldValues[string](cached, update, first, last), nil
+	case data.FieldTypeNullableString:
+		return mergeFieldValues[*string](cached, update, first, last), nil
+	case data.FieldTypeJSON:
+		return mergeFieldValues[json.RawMessage](cached, update, first, last), nil
+	case data.FieldTypeNullableJSON:
+		return mergeFieldValues[*json.RawMessage](cached, update, first, last), nil
+	default:
+		return nil, fmt.Errorf("field %s has unsupported type %s", cached.Name, cached.Type())
+	}
+}
+
+// Copies the typed values of a cached and an update field into one slice. The values are
+// read through pointers, which does not allocate for every row.
+func mergeFieldValues[T any](cached *data.Field, update *data.Field, first int, last int) *data.Field {
+	values := make([]T, 0, last-first+update.Len())
+	for row := first; row < last; row++ {
+		values = append(values, *cached.PointerAt(row).(*T))
+	}
+	for row := 0; row < update.Len(); row++ {
+		values = append(values, *update.PointerAt(row).(*T))
+	}
+
+	return copyFieldWithValues(cached, values)
+}
@@ -0,0 +1,146 @@
+package cds
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aveva/connect-data-services/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestIncrementalStreamsDataQuery(t *testing.T) {
+	basePath := "/api/" + apiVersion + "/tenants/" + tenantId + "/namespaces/" + namespaceId
+	mux := http.NewServeMux()
+	start := time.Date(2022, 6, 4, 0, 0, 0, 0, time.UTC)
+
+	mux.HandleFunc(basePath+"/streams/StreamId1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"TypeId": "StreamType1", "Id": "StreamId1", "Name": "StreamName1"}`))
+	})
+
+	mux.HandleFunc(basePath+"/types/StreamType1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"Id": "StreamType1",
+			"SdsTypeCode": 1,
+			"Properties": [
+				{"Id": "Timestamp", "IsKey": true, "SdsType": {"SdsTypeCode": 16}},
+				{"Id": "Value", "SdsType": {"SdsTypeCode": 14}}
+			]
+		}`))
+	})
+
+	// one event per hour, returning every event between the requested indexes
+	var startIndexes []string
+	mux.HandleFunc(basePath+"/streams/StreamId1/Data", func(w http.ResponseWriter, r *http.Request) {
+		startIndexes = append(startIndexes, r.URL.Query().Get("startIndex"))
+		from, _ := time.Parse(time.RFC3339, r.URL.Query().Get("startIndex"))
+		to, _ := time.Parse(time.RFC3339, r.URL.Query().Get("endIndex"))
+
+		body := "["
+		for timestamp := from; !timestamp.After(to); timestamp = timestamp.Add(time.Hour) {
+			if body != "[" {
+				body += ","
+			}
+			body += fmt.Sprintf(`{"Timestamp": "%s", "Value": %d}`, timestamp.Format(time.RFC3339), int(timestamp.Sub(start).Hours()))
+		}
+		body += "]"
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(body))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewCdsClient(server.URL, apiVersion, tenantId, "", "")
+	dataSource := CdsDataSource{
+		cdsClient:        &client,
+		settings:         &models.CdsSettings{NamespaceId: namespaceId},
+		incrementalCache: newQueryCache[*incrementalEntry](10, time.Minute),
+	}
+	qm := QueryModel{Collection: "streams", Id: "StreamId1", NamespaceId: namespaceId}
+
+	frame, err := dataSource.incrementalStreamsDataQuery(qm, backend.TimeRange{From: start, To: start.Add(3 * time.Hour)}, "token")
+	if err != nil || frame.Rows() != 4 {
+		t.Errorf("FAILED: expected 4 rows, got %v (%v)\n", frame, err)
+	}
+
+	// the window moves forward by two hours, so only the new events are requested
+	frame, err = dataSource.incrementalStreamsDataQuery(qm, backend.TimeRange{From: start.Add(2 * time.Hour), To: start.Add(5 * time.Hour)}, "token")
+	expected := data.NewFrame("StreamName1",
+		data.NewField("Timestamp", nil, []time.Time{start.Add(2 * time.Hour), start.Add(3 * time.Hour), start.Add(4 * time.Hour), start.Add(5 * time.Hour)}),
+		data.NewField("Value", nil, []float64{2, 3, 4, 5}),
+	).SetMeta(&data.FrameMeta{
+		Type:                data.FrameTypeTimeSeriesWide,
+		TypeVersion:         data.FrameTypeVersion{0, 1},
+		ExecutedQueryString: server.URL + basePath + "/streams/StreamId1/Data?startIndex=2022-06-04T03%3A00%3A00Z&endIndex=2022-06-04T05%3A00%3A00Z",
+	})
+	if err != nil || !reflect.DeepEqual(frame, expected) {
+		t.Errorf("FAILED: expected %v, got %v (%v)\n", expected, frame, err)
+	}
+
+	expectedIndexes := []string{start.Format(time.RFC3339), start.Add(3 * time.Hour).Format(time.RFC3339)}
+	if !reflect.DeepEqual(startIndexes, expectedIndexes) {
+		t.Errorf("FAILED: expected start indexes %v, got %v\n", expectedIndexes, startIndexes)
+	}
+
+	// a range starting before the cached data is fetched in full
+	_, err = dataSource.incrementalStreamsDataQuery(qm, backend.TimeRange{From: start, To: start.Add(5 * time.Hour)}, "token")
+	if err != nil || startIndexes[len(startIndexes)-1] != start.Format(time.RFC3339) {
+		t.Errorf("FAILED: expected full fetch from %v, got %v (%v)\n", start, startIndexes, err)
+	}
+
+	// a shorter range on the same stream has its own entry, and does not trim the entry of
+	// the longer range
+	_, err = dataSource.incrementalStreamsDataQuery(qm, backend.TimeRange{From: start.Add(4 * time.Hour), To: start.Add(5 * time.Hour)}, "token")
+	if err != nil || startIndexes[len(startIndexes)-1] != start.Add(4*time.Hour).Format(time.RFC3339) {
+		t.Errorf("FAILED: expected full fetch from %v, got %v (%v)\n", start.Add(4*time.Hour), startIndexes, err)
+	}
+
+	frame, err = dataSource.incrementalStreamsDataQuery(qm, backend.TimeRange{From: start.Add(time.Hour), To: start.Add(6 * time.Hour)}, "token")
+	if err != nil || frame.Rows() != 6 || startIndexes[len(startIndexes)-1] != start.Add(5*time.Hour).Format(time.RFC3339) {
+		t.Errorf("FAILED: expected 6 rows from an incremental fetch, got %v (%v, %v)\n", frame, startIndexes, err)
+	}
+}
+
+func TestMergeIncrementalFrames(t *testing.T) {
+	start := time.Date(2022, 6, 4, 0, 0, 0, 0, time.UTC)
+	hours := func(offsets ...int) []time.Time {
+		var times []time.Time
+		for _, offset := range offsets {
+			times = append(times, start.Add(time.Duration(offset)*time.Hour))
+		}
+		return times
+	}
+	value := func(v float64) *float64 { return &v }
+
+	conversionNotice := data.Notice{Severity: data.NoticeSeverityWarning, Text: "1 value of Value could not be converted"}
+	cached := data.NewFrame("StreamName1",
+		data.NewField("Timestamp", nil, hours(0, 1, 2, 3)),
+		data.NewField("Value", data.Labels{"stream": "StreamId1"}, []*float64{value(0), nil, value(2), value(3)}),
+	).SetMeta(&data.FrameMeta{Notices: []data.Notice{conversionNotice}})
+	update := data.NewFrame("StreamName1",
+		data.NewField("Timestamp", nil, hours(3, 4)),
+		data.NewField("Value", data.Labels{"stream": "StreamId1"}, []*float64{value(3), value(4)}),
+	).SetMeta(&data.FrameMeta{ExecutedQueryString: "update", Notices: []data.Notice{conversionNotice}})
+
+	// the cached rows before the range are trimmed and the rows from the fetch start are replaced
+	expected := data.NewFrame("StreamName1",
+		data.NewField("Timestamp", nil, hours(1, 2, 3, 4)),
+		data.NewField("Value", data.Labels{"stream": "StreamId1"}, []*float64{nil, value(2), value(3), value(4)}),
+	).SetMeta(&data.FrameMeta{ExecutedQueryString: "update", Notices: []data.Notice{conversionNotice}})
+
+	merged, err := mergeIncrementalFrames(cached, update, start.Add(time.Hour), start.Add(3*time.Hour))
+	if err != nil || !reflect.DeepEqual(merged, expected) {
+		t.Errorf("FAILED: expected %v, got %v (%v)\n", expected, merged, err)
+	}
+	if cached.Rows() != 4 {
+		t.Errorf("FAILED: expected the cached frame to be unchanged, got %v\n", cached)
+	}
+}
@@ -0,0 +1,172 @@
+package cds
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// Upper bound on cached metadata responses, to keep memory use predictable in large namespaces.
+const maxMetadataCacheEntries = 10000
+
+// Cache of stream and type metadata responses. Fresh entries are served without a request,
+// expired entries are revalidated using their ETag.
+type metadataCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*metadataCacheEntry
+}
+
+type metadataCacheEntry struct {
+	body    []byte
+	etag    string
+	expires time.Time
+}
+
+func newMetadataCache(ttl time.Duration) *metadataCache {
+	return &metadataCache{
+		ttl:     ttl,
+		entries: make(map[string]*metadataCacheEntry),
+	}
+}
+
+// Returns the cached entry for a key, and whether it is still fresh.
+func (c *metadataCache) get(key string) (*metadataCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	return entry, time.Now().Before(entry.expires)
+}
+
+func (c *metadataCache) set(key string, body []byte, etag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// make room by dropping expired entries, or everything if all entries are fresh
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxMetadataCacheEntries {
+		now := time.Now()
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxMetadataCacheEntries {
+			c.entries = make(map[string]*metadataCacheEntry)
+		}
+	}
+
+	c.entries[key] = &metadataCacheEntry{
+		body:    body,
+		etag:    etag,
+		expires: time.Now().Add(c.ttl),
+	}
+}
+
+// Requests a metadata resource such as a stream or type and parses it into v. When the
+// client has a metadata cache, fresh responses are reused and expired responses are
+// revalidated with If-None-Match.
+func cachedSdsRequest(d *CdsClient, token string, path string, headers map[string]string, v interface{}) error {
+	var body []byte
+	var err error
+	if d.metadataCache == nil {
+		body, err = SdsRequest(d, token, path, headers)
+	} else {
+		body, err = revalidateSdsRequest(d, token, path, headers)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		log.DefaultLogger.Warn("Error parsing json", err.Error())
+		log.DefaultLogger.Warn(fmt.Sprint(string(body)))
+		return err
+	}
+
+	return nil
+}
+
+// Requests a metadata resource that may not exist, such as the metadata or tags of a
+// stream. A 404 Not Found response leaves v empty and is cached like any other response,
+// so that missing resources are not requested again until the entry expires.
+func cachedOptionalSdsRequest(d *CdsClient, token string, path string, headers map[string]string, v interface{}) error {
+	err := cachedSdsRequest(d, token, path, headers, v)
+	if !isNotFound(err) {
+		return err
+	}
+
+	if d.metadataCache != nil {
+		d.metadataCache.set(metadataCacheKey(path, headers, token), []byte("null"), "")
+	}
+	return nil
+}
+
+func revalidateSdsRequest(d *CdsClient, token string, path string, headers map[string]string) ([]byte, error) {
+	key := metadataCacheKey(path, headers, token)
+	entry, fresh := d.metadataCache.get(key)
+	if fresh {
+		return entry.body, nil
+	}
+
+	requestHeaders := make(map[string]string, len(headers)+1)
+	for k, v := range headers {
+		requestHeaders[k] = v
+	}
+	if entry != nil && entry.etag != "" {
+		requestHeaders["If-None-Match"] = entry.etag
+	}
+
+	body, resp, err := sdsRequest(d, token, path, requestHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		if entry == nil {
+			return nil, fmt.Errorf("unexpected Not Modified response for %s", path)
+		}
+		d.metadataCache.set(key, entry.body, entry.etag)
+		return entry.body, nil
+	}
+
+	d.metadataCache.set(key, body, resp.Header.Get("ETag"))
+	return body, nil
+}
+
+// Builds the metadata cache key from the request path, headers and a hash of the token,
+// so that cached metadata is only reused for the identity that requested it.
+func metadataCacheKey(path string, headers map[string]string, token string) string {
+	parts := []string{path}
+
+	headerNames := make([]string, 0, len(headers))
+	for k := range headers {
+		headerNames = append(headerNames, k)
+	}
+	sort.Strings(headerNames)
+	for _, k := range headerNames {
+		parts = append(parts, k+"="+headers[k])
+	}
+
+	parts = append(parts, hashToken(token))
+	return strings.Join(parts, "|")
+}
+
+// Hashes a token so that it can be used in cache keys without being kept in plain text.
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,110 @@
+package cds
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// Ranges ending within this window of the current time are treated as ending at "now"
+// and are never served from the query cache, since their newest data is still arriving.
+const liveRangeTolerance = time.Minute
+
+// Bounded least recently used cache with per entry expiry, used for stream data query results.
+type queryCache[V any] struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type queryCacheEntry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+func newQueryCache[V any](size int, ttl time.Duration) *queryCache[V] {
+	return &queryCache[V]{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Returns the cached value for a key if it exists and has not expired.
+func (c *queryCache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var empty V
+	element, ok := c.entries[key]
+	if !ok {
+		return empty, false
+	}
+
+	entry := element.Value.(*queryCacheEntry[V])
+	if time.Now().After(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return empty, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// Adds or replaces a cached value, evicting the least recently used entry when full.
+func (c *queryCache[V]) set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*queryCacheEntry[V])
+		entry.value = value
+		entry.expires = time.Now().Add(c.ttl)
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&queryCacheEntry[V]{
+		key:     key,
+		value:   value,
+		expires: time.Now().Add(c.ttl),
+	})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*queryCacheEntry[V]).key)
+	}
+}
+
+// Builds the cache key for a stream data query. The time range is aligned to whole
+// seconds so that equivalent dashboard ranges share an entry. When the token is not
+// empty it is hashed into the key so results are never shared between identities.
+func queryCacheKey(mode string, scopeId string, streamId string, timeRange backend.TimeRange, token string) string {
+	parts := []string{
+		mode,
+		scopeId,
+		streamId,
+		timeRange.From.UTC().Truncate(time.Second).Format(time.RFC3339),
+		timeRange.To.UTC().Truncate(time.Second).Format(time.RFC3339),
+	}
+
+	if token != "" {
+		parts = append(parts, hashToken(token))
+	}
+
+	return strings.Join(parts, "|")
+}
+
+// Determines whether a time range ends at the current time.
+func isLiveRange(timeRange backend.TimeRange, now time.Time) bool {
+	return timeRange.To.After(now.Add(-liveRangeTolerance))
+}
@@ -0,0 +1,58 @@
+package cds
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestQueryCache(t *testing.T) {
+	cache := newQueryCache[*data.Frame](2, time.Minute)
+	cache.set("a", data.NewFrame("a"))
+	cache.set("b", data.NewFrame("b"))
+
+	// reading "a" makes "b" the least recently used entry
+	if _, ok := cache.get("a"); !ok {
+		t.Errorf("FAILED: expected entry a to be cached")
+	}
+
+	cache.set("c", data.NewFrame("c"))
+
+	if _, ok := cache.get("b"); ok {
+		t.Errorf("FAILED: expected entry b to be evicted")
+	}
+	if frame, ok := cache.get("c"); !ok || frame.Name != "c" {
+		t.Errorf("FAILED: expected entry c to be cached, got %v\n", frame)
+	}
+
+	expired := newQueryCache[*data.Frame](2, -time.Second)
+	expired.set("a", data.NewFrame("a"))
+	if _, ok := expired.get("a"); ok {
+		t.Errorf("FAILED: expected entry a to be expired")
+	}
+}
+
+func TestQueryCacheKey(t *testing.T) {
+	now := time.Date(2022, 6, 5, 12, 0, 0, 0, time.UTC)
+	timeRange := backend.TimeRange{From: now.Add(-time.Hour), To: now}
+	shifted := backend.TimeRange{From: timeRange.From.Add(250 * time.Millisecond), To: timeRange.To.Add(250 * time.Millisecond)}
+
+	if queryCacheKey("namespace", namespaceId, "StreamId1", timeRange, "") != queryCacheKey("namespace", namespaceId, "StreamId1", shifted, "") {
+		t.Errorf("FAILED: expected sub-second differences to share a key")
+	}
+	if queryCacheKey("namespace", namespaceId, "StreamId1", timeRange, "") == queryCacheKey("community", namespaceId, "StreamId1", timeRange, "") {
+		t.Errorf("FAILED: expected query modes to use different keys")
+	}
+	if queryCacheKey("namespace", namespaceId, "StreamId1", timeRange, "token1") == queryCacheKey("namespace", namespaceId, "StreamId1", timeRange, "token2") {
+		t.Errorf("FAILED: expected identities to use different keys")
+	}
+
+	if !isLiveRange(timeRange, now) {
+		t.Errorf("FAILED: expected range ending now to be live")
+	}
+	if isLiveRange(timeRange, now.Add(time.Hour)) {
+		t.Errorf("FAILED: expected historical range not to be live")
+	}
+}
@@ -1,8 +1,9 @@
 package sds
 
 type SdsStream struct {
-	TypeId      string `json:"TypeId"`
-	Id          string `json:"Id"`
-	Name        string `json:"Name"`
-	Description string `json:"Description"`
+	TypeId            string                      `json:"TypeId"`
+	Id                string                      `json:"Id"`
+	Name              string                      `json:"Name"`
+	Description       string                      `json:"Description"`
+	PropertyOverrides []SdsStreamPropertyOverride `json:"PropertyOverrides"`
 }
@@ -0,0 +1,7 @@
+package sds
+
+type SdsStreamPropertyOverride struct {
+	SdsTypePropertyId string `json:"SdsTypePropertyId"`
+	Uom               string `json:"Uom"`
+	InterpolationMode string `json:"InterpolationMode"`
+}
@@ -0,0 +1,10 @@
+package sds
+
+type SdsStreamView struct {
+	Id           string                  `json:"Id"`
+	Name         string                  `json:"Name"`
+	Description  string                  `json:"Description"`
+	SourceTypeId string                  `json:"SourceTypeId"`
+	TargetTypeId string                  `json:"TargetTypeId"`
+	Properties   []SdsStreamViewProperty `json:"Properties"`
+}
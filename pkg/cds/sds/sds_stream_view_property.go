@@ -0,0 +1,6 @@
+package sds
+
+type SdsStreamViewProperty struct {
+	SourceId string `json:"SourceId"`
+	TargetId string `json:"TargetId"`
+}
@@ -1,8 +1,47 @@
 package sds
 
+import (
+	"sort"
+)
+
 type SdsType struct {
-	Id          string            `json:"Id"`
-	SdsTypeCode SdsTypeCode       `json:"SdsTypeCode"`
-	Name        string            `json:"Name"`
-	Properties  []SdsTypeProperty `json:"Properties"`
+	Id               string            `json:"Id"`
+	SdsTypeCode      SdsTypeCode       `json:"SdsTypeCode"`
+	Name             string            `json:"Name"`
+	Description      string            `json:"Description"`
+	Properties       []SdsTypeProperty `json:"Properties"`
+	GenericArguments []SdsType         `json:"GenericArguments"`
+}
+
+// Returns the key properties that make up the index of the type, in index order.
+// A type with more than one key property has a compound index.
+func (sdsType SdsType) Index() []SdsTypeProperty {
+	var index []SdsTypeProperty
+	for _, property := range sdsType.Properties {
+		if property.IsKey {
+			index = append(index, property)
+		}
+	}
+
+	sort.SliceStable(index, func(i, j int) bool {
+		return index[i].Order < index[j].Order
+	})
+
+	return index
+}
+
+// Determines whether the primary index of the type is a DateTime or DateTimeOffset, so
+// that the dashboard time range can be used as the index range.
+func (sdsType SdsType) IsTimeIndexed() bool {
+	index := sdsType.Index()
+	if len(index) == 0 {
+		return false
+	}
+
+	switch index[0].SdsType.SdsTypeCode {
+	case "DateTime", "DateTimeOffset":
+		return true
+	default:
+		return false
+	}
 }
@@ -104,3 +104,15 @@ func (sdsTypeCode *SdsTypeCode) UnmarshalJSON(b []byte) error {
 
 	return nil
 }
+
+// Returns the nullable form of a type code, or the code itself when it has none.
+func (sdsTypeCode SdsTypeCode) Nullable() SdsTypeCode {
+	nullable := "Nullable" + string(sdsTypeCode)
+	for _, code := range sdsTypeCodes {
+		if code == nullable {
+			return SdsTypeCode(nullable)
+		}
+	}
+
+	return sdsTypeCode
+}
@@ -1,7 +1,11 @@
 package sds
 
 type SdsTypeProperty struct {
-	Id      string  `json:"Id"`
-	Name    string  `json:"Name"`
-	SdsType SdsType `json:"SdsType"`
+	Id          string  `json:"Id"`
+	Name        string  `json:"Name"`
+	Description string  `json:"Description"`
+	SdsType     SdsType `json:"SdsType"`
+	IsKey       bool    `json:"IsKey"`
+	Order       int     `json:"Order"`
+	Uom         string  `json:"Uom"`
 }
@@ -0,0 +1,530 @@
+package cds
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aveva/connect-data-services/pkg/cds/sds"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Types nested deeper than this are kept as JSON instead of being flattened.
+const maxNestingDepth = 8
+
+// Array elements beyond this index are not expanded into fields.
+const maxArrayElements = 64
+
+// Accumulates the values of one SdsType property into typed slices, so that frames are
+// built column by column instead of row by row.
+type sdsColumn interface {
+	// Appends a decoded JSON value, where nil represents a null or missing value.
+	append(value interface{})
+	// Creates the fields of the column, more than one for nested types and arrays.
+	fields(path fieldPath) []*data.Field
+	// Describes values that could not be converted, for the fields created with name.
+	notices(name string) []data.Notice
+}
+
+// Name and metadata of the fields created from a column, built up from the properties on
+// the path to the column.
+type fieldPath struct {
+	name        string
+	displayName string
+	description string
+	unit        string
+	uom         string
+}
+
+// Key of the field config custom values holding the SDS unit of measure of a field, which
+// unit conversions start from since several SDS units share a Grafana unit.
+const uomConfigKey = "uom"
+
+// Returns the path of a property nested in the path. The display name uses the property
+// names, and the description and unit come from the property.
+func (p fieldPath) property(property sds.SdsTypeProperty) fieldPath {
+	displayName := property.Name
+	if displayName == "" {
+		displayName = property.Id
+	}
+
+	description := property.Description
+	if description == "" {
+		description = property.SdsType.Description
+	}
+
+	name := property.Id
+	if p.name != "" {
+		name = p.name + "." + property.Id
+		displayName = p.displayName + "." + displayName
+	}
+
+	return fieldPath{
+		name:        name,
+		displayName: displayName,
+		description: description,
+		unit:        grafanaUnit(property.Uom),
+		uom:         property.Uom,
+	}
+}
+
+// Returns the path of an array element, which shares the metadata of the array.
+func (p fieldPath) element(index int) fieldPath {
+	p.name = fmt.Sprintf("%s[%d]", p.name, index)
+	p.displayName = fmt.Sprintf("%s[%d]", p.displayName, index)
+	return p
+}
+
+// Creates a field named with the path, with a field config holding the metadata of the
+// path. The display name is only set when it differs from the name, and the unit and unit
+// of measure of the path only when the column does not set a unit of its own.
+func (p fieldPath) newField(values interface{}, config *data.FieldConfig) *data.Field {
+	field := data.NewField(p.name, nil, values)
+	field.Config = config
+
+	ownUnit := config != nil && config.Unit != ""
+	if p.displayName != p.name || p.description != "" || (p.unit != "" && !ownUnit) {
+		if config == nil {
+			field.Config = &data.FieldConfig{}
+		} else {
+			copied := *config
+			field.Config = &copied
+		}
+
+		if p.displayName != p.name {
+			field.Config.DisplayNameFromDS = p.displayName
+		}
+		field.Config.Description = p.description
+		if !ownUnit {
+			field.Config.Unit = p.unit
+			if p.uom != "" {
+				field.Config.Custom = map[string]interface{}{uomConfigKey: p.uom}
+			}
+		}
+	}
+
+	return field
+}
+
+// Returns the SDS unit of measure of a field, or an empty string for fields without one.
+func fieldUom(field *data.Field) string {
+	if field.Config == nil {
+		return ""
+	}
+
+	uom, _ := field.Config.Custom[uomConfigKey].(string)
+	return uom
+}
+
+// Column that reads its values from the decoder itself, used for complex properties.
+type decodingSdsColumn interface {
+	sdsColumn
+	decode(decoder *json.Decoder) error
+}
+
+// Column of values converted to a Go type. Values that cannot be converted are stored as
+// the zero value, or nil for nullable columns, and counted so they can be reported.
+type typedColumn[T any] struct {
+	values     []T
+	convert    func(value interface{}) (T, error)
+	config     *data.FieldConfig
+	errorCount int
+	firstError error
+}
+
+func (c *typedColumn[T]) append(value interface{}) {
+	converted, err := c.convert(value)
+	if err != nil {
+		c.errorCount++
+		if c.firstError == nil {
+			c.firstError = err
+		}
+	}
+	c.values = append(c.values, converted)
+}
+
+func (c *typedColumn[T]) fields(path fieldPath) []*data.Field {
+	return []*data.Field{path.newField(c.values, c.config)}
+}
+
+func (c *typedColumn[T]) notices(name string) []data.Notice {
+	if c.errorCount == 0 {
+		return nil
+	}
+
+	values := "values"
+	if c.errorCount == 1 {
+		values = "value"
+	}
+
+	return []data.Notice{{
+		Severity: data.NoticeSeverityWarning,
+		Text:     fmt.Sprintf("%d %s of %s could not be converted, the first error was: %v", c.errorCount, values, name, c.firstError),
+	}}
+}
+
+func newTypedColumn[T any](capacity int, convert func(value interface{}) (T, error)) *typedColumn[T] {
+	return &typedColumn[T]{
+		values:  make([]T, 0, capacity),
+		convert: convert,
+	}
+}
+
+// Column that keeps the undecoded JSON of each value, used as the fallback for
+// dictionaries and types that cannot be flattened.
+type jsonColumn struct {
+	values []*json.RawMessage
+}
+
+func (c *jsonColumn) append(value interface{}) {
+	c.values = append(c.values, nil)
+}
+
+func (c *jsonColumn) decode(decoder *json.Decoder) error {
+	var value json.RawMessage
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+
+	if bytes.Equal(value, []byte("null")) {
+		c.values = append(c.values, nil)
+	} else {
+		c.values = append(c.values, &value)
+	}
+	return nil
+}
+
+func (c *jsonColumn) fields(path fieldPath) []*data.Field {
+	return []*data.Field{path.newField(c.values, nil)}
+}
+
+func (c *jsonColumn) notices(name string) []data.Notice {
+	return nil
+}
+
+func newJsonColumn(capacity int) *jsonColumn {
+	return &jsonColumn{values: make([]*json.RawMessage, 0, capacity)}
+}
+
+// Column for a type with properties, flattened into one column per property named
+// with the property path, such as Position.X.
+type objectColumn struct {
+	properties []sds.SdsTypeProperty
+	columns    []sdsColumn
+	indexes    map[string]int
+	seen       []bool
+}
+
+func newObjectColumn(properties []sds.SdsTypeProperty, capacity int, depth int) *objectColumn {
+	column := &objectColumn{
+		properties: properties,
+		columns:    make([]sdsColumn, len(properties)),
+		indexes:    make(map[string]int, len(properties)),
+		seen:       make([]bool, len(properties)),
+	}
+
+	for i, property := range properties {
+		column.columns[i] = newPropertyColumn(property.SdsType, capacity, depth)
+		column.indexes[property.Id] = i
+	}
+
+	return column
+}
+
+func (c *objectColumn) append(value interface{}) {
+	for _, column := range c.columns {
+		column.append(nil)
+	}
+}
+
+func (c *objectColumn) decode(decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != json.Delim('{') {
+		c.append(nil)
+		return skipNested(decoder, token)
+	}
+
+	for i := range c.seen {
+		c.seen[i] = false
+	}
+
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		// only the first occurrence of a property is used
+		index, ok := c.indexes[token.(string)]
+		if !ok || c.seen[index] {
+			if _, err = decodeScalar(decoder); err != nil {
+				return err
+			}
+			continue
+		}
+
+		c.seen[index] = true
+		if err = decodeInto(decoder, c.columns[index]); err != nil {
+			return err
+		}
+	}
+
+	// consume the end of the object
+	if _, err = decoder.Token(); err != nil {
+		return err
+	}
+
+	for i, seen := range c.seen {
+		if !seen {
+			c.columns[i].append(nil)
+		}
+	}
+
+	return nil
+}
+
+func (c *objectColumn) fields(path fieldPath) []*data.Field {
+	var fields []*data.Field
+	for i, column := range c.columns {
+		fields = append(fields, column.fields(path.property(c.properties[i]))...)
+	}
+
+	return fields
+}
+
+func (c *objectColumn) notices(name string) []data.Notice {
+	var notices []data.Notice
+	for i, column := range c.columns {
+		propertyName := c.properties[i].Id
+		if name != "" {
+			propertyName = name + "." + c.properties[i].Id
+		}
+		notices = append(notices, column.notices(propertyName)...)
+	}
+
+	return notices
+}
+
+// Column for an array property, expanded into one nullable column per element index,
+// such as Values[0]. Element columns are added as longer arrays are decoded.
+type arrayColumn struct {
+	newElement func(index int) sdsColumn
+	elements   []sdsColumn
+	rows       int
+}
+
+func (c *arrayColumn) append(value interface{}) {
+	for _, element := range c.elements {
+		element.append(nil)
+	}
+	c.rows++
+}
+
+func (c *arrayColumn) decode(decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != json.Delim('[') {
+		c.append(nil)
+		return skipNested(decoder, token)
+	}
+
+	i := 0
+	for ; decoder.More(); i++ {
+		if i >= maxArrayElements {
+			if _, err = decodeScalar(decoder); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// add a column for a new index, with nulls for the previous rows
+		if i == len(c.elements) {
+			element := c.newElement(i)
+			for row := 0; row < c.rows; row++ {
+				element.append(nil)
+			}
+			c.elements = append(c.elements, element)
+		}
+
+		if err = decodeInto(decoder, c.elements[i]); err != nil {
+			return err
+		}
+	}
+
+	// consume the end of the array
+	if _, err = decoder.Token(); err != nil {
+		return err
+	}
+
+	for ; i < len(c.elements); i++ {
+		c.elements[i].append(nil)
+	}
+	c.rows++
+
+	return nil
+}
+
+func (c *arrayColumn) fields(path fieldPath) []*data.Field {
+	var fields []*data.Field
+	for i, element := range c.elements {
+		fields = append(fields, element.fields(path.element(i))...)
+	}
+
+	return fields
+}
+
+func (c *arrayColumn) notices(name string) []data.Notice {
+	var notices []data.Notice
+	for i, element := range c.elements {
+		notices = append(notices, element.notices(fmt.Sprintf("%s[%d]", name, i))...)
+	}
+
+	return notices
+}
+
+// Creates the column for a property type, flattening nested types and expanding arrays.
+func newPropertyColumn(sdsType sds.SdsType, capacity int, depth int) sdsColumn {
+	if depth >= maxNestingDepth {
+		return newJsonColumn(capacity)
+	}
+
+	// properties of nested objects are nullable, since the object can be null or missing
+	if sdsType.SdsTypeCode == "Object" && len(sdsType.Properties) > 0 {
+		return newObjectColumn(nullableSdsType(sdsType).Properties, capacity, depth+1)
+	}
+
+	if elementType, ok := arrayElementType(sdsType); ok {
+		// elements are nullable, since arrays can have different lengths
+		// only the first element column is preallocated, the others grow as they are
+		// decoded, so that long arrays do not reserve room for every row of every element
+		elementType = nullableSdsType(elementType)
+		return &arrayColumn{
+			newElement: func(index int) sdsColumn {
+				if index > 0 {
+					return newPropertyColumn(elementType, 0, depth+1)
+				}
+				return newPropertyColumn(elementType, capacity, depth+1)
+			},
+		}
+	}
+
+	return newSdsColumn(sdsType.SdsTypeCode, capacity)
+}
+
+// Returns a copy of a type where the type and all nested property types are nullable.
+func nullableSdsType(sdsType sds.SdsType) sds.SdsType {
+	sdsType.SdsTypeCode = sdsType.SdsTypeCode.Nullable()
+
+	properties := make([]sds.SdsTypeProperty, len(sdsType.Properties))
+	for i, property := range sdsType.Properties {
+		property.SdsType = nullableSdsType(property.SdsType)
+		properties[i] = property
+	}
+	sdsType.Properties = properties
+
+	return sdsType
+}
+
+// Returns the element type of an array type. Dictionaries, and generic collections
+// without a known element type, are not expanded.
+func arrayElementType(sdsType sds.SdsType) (sds.SdsType, bool) {
+	switch t := sdsType.SdsTypeCode; t {
+	case "Array", "IList", "IEnumerable":
+		if len(sdsType.GenericArguments) == 0 {
+			return sds.SdsType{}, false
+		}
+		return sdsType.GenericArguments[0], true
+	default:
+		if strings.HasSuffix(string(t), "Array") {
+			return sds.SdsType{SdsTypeCode: sds.SdsTypeCode(strings.TrimSuffix(string(t), "Array"))}, true
+		}
+		return sds.SdsType{}, false
+	}
+}
+
+type number interface {
+	~int8 | ~uint8 | ~int16 | ~uint16 | ~int32 | ~uint32 | ~int64 | ~uint64 | ~float32 | ~float64
+}
+
+// Creates the column for an SdsTypeCode, with room for the expected number of rows.
+// Every code in sds_type_code.go is mapped to the closest Grafana field type: numbers for
+// integers, decimals and enumerations, time for DateTime and DateTimeOffset, milliseconds
+// for TimeSpan, strings for characters, Guid and Version, and JSON for complex codes.
+func newSdsColumn(sdsTypeCode sds.SdsTypeCode, capacity int) sdsColumn {
+	switch t := sdsTypeCode; t {
+	case "DateTime", "DateTimeOffset":
+		return newTypedColumn(capacity, toTime)
+	case "NullableDateTime", "NullableDateTimeOffset":
+		return newTypedColumn(capacity, toNullable(toTime))
+	case "TimeSpan":
+		return withUnit(newTypedColumn(capacity, toTimeSpan), "ms")
+	case "NullableTimeSpan":
+		return withUnit(newTypedColumn(capacity, toNullable(toTimeSpan)), "ms")
+	case "Boolean":
+		return newTypedColumn(capacity, toBool)
+	case "NullableBoolean":
+		return newTypedColumn(capacity, toNullable(toBool))
+	case "SByte", "SByteEnum":
+		return newTypedColumn(capacity, toNumber[int8])
+	case "NullableSByte", "NullableSByteEnum":
+		return newTypedColumn(capacity, toNullable(toNumber[int8]))
+	case "Byte", "ByteEnum":
+		return newTypedColumn(capacity, toNumber[uint8])
+	case "NullableByte", "NullableByteEnum":
+		return newTypedColumn(capacity, toNullable(toNumber[uint8]))
+	case "Int16", "Int16Enum":
+		return newTypedColumn(capacity, toNumber[int16])
+	case "NullableInt16", "NullableInt16Enum":
+		return newTypedColumn(capacity, toNullable(toNumber[int16]))
+	case "UInt16", "UInt16Enum":
+		return newTypedColumn(capacity, toNumber[uint16])
+	case "NullableUInt16", "NullableUInt16Enum":
+		return newTypedColumn(capacity, toNullable(toNumber[uint16]))
+	case "Int32", "Int32Enum":
+		return newTypedColumn(capacity, toNumber[int32])
+	case "NullableInt32", "NullableInt32Enum":
+		return newTypedColumn(capacity, toNullable(toNumber[int32]))
+	case "UInt32", "UInt32Enum":
+		return newTypedColumn(capacity, toNumber[uint32])
+	case "NullableUInt32", "NullableUInt32Enum":
+		return newTypedColumn(capacity, toNullable(toNumber[uint32]))
+	case "Int64", "Int64Enum":
+		return newTypedColumn(capacity, toNumber[int64])
+	case "NullableInt64", "NullableInt64Enum":
+		return newTypedColumn(capacity, toNullable(toNumber[int64]))
+	case "UInt64", "UInt64Enum":
+		return newTypedColumn(capacity, toNumber[uint64])
+	case "NullableUInt64", "NullableUInt64Enum":
+		return newTypedColumn(capacity, toNullable(toNumber[uint64]))
+	case "Single":
+		return newTypedColumn(capacity, toNumber[float32])
+	case "NullableSingle":
+		return newTypedColumn(capacity, toNullable(toNumber[float32]))
+	case "Double", "Decimal":
+		return newTypedColumn(capacity, toNumber[float64])
+	case "NullableDouble", "NullableDecimal":
+		return newTypedColumn(capacity, toNullable(toNumber[float64]))
+	case "Object", "Array", "IList", "IDictionary", "IEnumerable", "SdsType", "SdsTypeProperty":
+		return newJsonColumn(capacity)
+	default:
+		// arrays of simple types
+		if strings.HasSuffix(string(t), "Array") {
+			return newJsonColumn(capacity)
+		}
+
+		// String, Char, Guid, Version, Empty and DBNull
+		return newTypedColumn(capacity, toNullable(toString))
+	}
+}
+
+// Sets the Grafana unit of the field created from a column.
+func withUnit[T any](column *typedColumn[T], unit string) *typedColumn[T] {
+	column.config = &data.FieldConfig{Unit: unit}
+	return column
+}
@@ -0,0 +1,207 @@
+package cds
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aveva/connect-data-services/pkg/cds/sds"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestSdsTypeCodeConversion(t *testing.T) {
+	timestamp := time.Date(2022, 6, 4, 1, 2, 3, 0, time.UTC)
+	offsetTimestamp := time.Date(2022, 6, 4, 1, 2, 3, 0, time.FixedZone("", 2*60*60))
+	int8Value, uint8Value, int64Value, uint64Value := int8(-5), uint8(200), int64(-9), uint64(9)
+	float64Value, float32Value := 1.25, float32(1.25)
+	stringValue := "a"
+	trueValue := true
+	rawValue := json.RawMessage(`[1,2]`)
+	rawObject := json.RawMessage(`{"a":1}`)
+	msConfig := &data.FieldConfig{Unit: "ms"}
+
+	tests := []struct {
+		sdsTypeCode sds.SdsTypeCode
+		value       string
+		expected    *data.Field
+	}{
+		{"DateTime", `"2022-06-04T01:02:03Z"`, data.NewField("Value", nil, []time.Time{timestamp})},
+		{"NullableDateTime", `null`, data.NewField("Value", nil, []*time.Time{nil})},
+		{"DateTimeOffset", `"2022-06-04T01:02:03+02:00"`, data.NewField("Value", nil, []time.Time{offsetTimestamp})},
+		{"NullableDateTimeOffset", `"2022-06-04T01:02:03+02:00"`, data.NewField("Value", nil, []*time.Time{&offsetTimestamp})},
+		{"TimeSpan", `"1.02:03:04.5"`, data.NewField("Value", nil, []float64{26*60*60*1000 + 3*60*1000 + 4500}).SetConfig(msConfig)},
+		{"Boolean", `false`, data.NewField("Value", nil, []bool{false})},
+		{"NullableBoolean", `"True"`, data.NewField("Value", nil, []*bool{&trueValue})},
+		{"SByte", `-5`, data.NewField("Value", nil, []int8{-5})},
+		{"NullableSByte", `-5`, data.NewField("Value", nil, []*int8{&int8Value})},
+		{"Byte", `200`, data.NewField("Value", nil, []uint8{200})},
+		{"NullableByteEnum", `200`, data.NewField("Value", nil, []*uint8{&uint8Value})},
+		{"Int16Enum", `3`, data.NewField("Value", nil, []int16{3})},
+		{"UInt16", `3`, data.NewField("Value", nil, []uint16{3})},
+		{"Int32Enum", `3`, data.NewField("Value", nil, []int32{3})},
+		{"UInt32Enum", `3`, data.NewField("Value", nil, []uint32{3})},
+		{"NullableInt64Enum", `-9`, data.NewField("Value", nil, []*int64{&int64Value})},
+		{"NullableUInt64", `9`, data.NewField("Value", nil, []*uint64{&uint64Value})},
+		{"UInt64Enum", `9`, data.NewField("Value", nil, []uint64{9})},
+		{"Single", `1.25`, data.NewField("Value", nil, []float32{1.25})},
+		{"NullableSingle", `1.25`, data.NewField("Value", nil, []*float32{&float32Value})},
+		{"Decimal", `1.25`, data.NewField("Value", nil, []float64{1.25})},
+		{"NullableDecimal", `1.25`, data.NewField("Value", nil, []*float64{&float64Value})},
+		{"Char", `"a"`, data.NewField("Value", nil, []*string{&stringValue})},
+		{"NullableChar", `null`, data.NewField("Value", nil, []*string{nil})},
+		{"Guid", `"a"`, data.NewField("Value", nil, []*string{&stringValue})},
+		{"Version", `"a"`, data.NewField("Value", nil, []*string{&stringValue})},
+		{"DBNull", `null`, data.NewField("Value", nil, []*string{nil})},
+		{"Object", `{"a":1}`, data.NewField("Value", nil, []*json.RawMessage{&rawObject})},
+		{"IList", `[1,2]`, data.NewField("Value", nil, []*json.RawMessage{&rawValue})},
+		{"IDictionary", `null`, data.NewField("Value", nil, []*json.RawMessage{nil})},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.sdsTypeCode), func(t *testing.T) {
+			sdsType := sds.SdsType{
+				Properties: []sds.SdsTypeProperty{
+					{Id: "Value", SdsType: sds.SdsType{SdsTypeCode: test.sdsTypeCode}},
+				},
+			}
+
+			frame, err := createDataFrameFromSdsData("test", sdsType, []byte(`[{"Value": `+test.value+`}]`), "")
+			if err != nil {
+				t.Fatalf("FAILED: unexpected error %v\n", err)
+			}
+
+			if !reflect.DeepEqual(frame.Fields[0], test.expected) {
+				t.Errorf("FAILED: expected %v, got %v\n", test.expected, frame.Fields[0])
+			}
+		})
+	}
+}
+
+func TestParseTimeSpan(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected time.Duration
+	}{
+		{"00:00:01", time.Second},
+		{"01:02:03.5", time.Hour + 2*time.Minute + 3500*time.Millisecond},
+		{"2.00:00:00", 48 * time.Hour},
+		{"-00:01:00", -time.Minute},
+	}
+
+	for _, test := range tests {
+		duration, err := parseTimeSpan(test.text)
+		if err != nil || duration != test.expected {
+			t.Errorf("FAILED: expected %v for %s, got %v (%v)\n", test.expected, test.text, duration, err)
+		}
+	}
+
+	if _, err := parseTimeSpan("1 day"); err == nil {
+		t.Errorf("FAILED: expected an error for an invalid TimeSpan")
+	}
+}
+
+func TestNestedSdsTypeFlattening(t *testing.T) {
+	double := sds.SdsType{SdsTypeCode: "Double"}
+	sdsType := sds.SdsType{
+		Properties: []sds.SdsTypeProperty{
+			{Id: "Timestamp", IsKey: true, SdsType: sds.SdsType{SdsTypeCode: "DateTime"}},
+			{Id: "Position", SdsType: sds.SdsType{SdsTypeCode: "Object", Properties: []sds.SdsTypeProperty{
+				{Id: "X", SdsType: double},
+				{Id: "Y", SdsType: double},
+			}}},
+			{Id: "Values", SdsType: sds.SdsType{SdsTypeCode: "DoubleArray"}},
+			{Id: "Readings", SdsType: sds.SdsType{SdsTypeCode: "IList", GenericArguments: []sds.SdsType{
+				{SdsTypeCode: "Object", Properties: []sds.SdsTypeProperty{{Id: "Value", SdsType: double}}},
+			}}},
+			{Id: "Tags", SdsType: sds.SdsType{SdsTypeCode: "IDictionary"}},
+		},
+	}
+
+	body := []byte(`[
+		{
+			"Timestamp": "2022-06-04T00:00:00Z",
+			"Position": {"X": 1, "Y": 2},
+			"Values": [1, 2],
+			"Readings": [{"Value": 5}],
+			"Tags": {"a": "b"}
+		},
+		{
+			"Timestamp": "2022-06-05T00:00:00Z",
+			"Position": null,
+			"Values": [3, 4, 5]
+		}
+	]`)
+
+	one, two, three, four, five := 1.0, 2.0, 3.0, 4.0, 5.0
+	tags := json.RawMessage(`{"a": "b"}`)
+	expected := data.NewFrame("test",
+		data.NewField("Timestamp", nil, []time.Time{time.Date(2022, 6, 4, 0, 0, 0, 0, time.UTC), time.Date(2022, 6, 5, 0, 0, 0, 0, time.UTC)}),
+		data.NewField("Position.X", nil, []*float64{&one, nil}),
+		data.NewField("Position.Y", nil, []*float64{&two, nil}),
+		data.NewField("Values[0]", nil, []*float64{&one, &three}),
+		data.NewField("Values[1]", nil, []*float64{&two, &four}),
+		data.NewField("Values[2]", nil, []*float64{nil, &five}),
+		data.NewField("Readings[0].Value", nil, []*float64{&five, nil}),
+		data.NewField("Tags", nil, []*json.RawMessage{&tags, nil}),
+	).SetMeta(&data.FrameMeta{Type: data.FrameTypeTable, TypeVersion: data.FrameTypeVersion{0, 1}})
+
+	frame, err := createDataFrameFromSdsData("test", sdsType, body, "")
+	if err != nil || !reflect.DeepEqual(frame, expected) {
+		t.Errorf("FAILED: expected %v, got %v (%v)\n", expected, frame, err)
+	}
+}
+
+func TestArrayColumnCapacity(t *testing.T) {
+	column := newPropertyColumn(sds.SdsType{SdsTypeCode: "DoubleArray"}, 1000, 0).(*arrayColumn)
+	decoder := json.NewDecoder(strings.NewReader(`[1, 2, 3]`))
+	decoder.UseNumber()
+	if err := column.decode(decoder); err != nil || len(column.elements) != 3 {
+		t.Fatalf("FAILED: expected 3 element columns, got %d (%v)\n", len(column.elements), err)
+	}
+
+	// only the first element column reserves room for every row
+	for i, element := range column.elements {
+		expected := 1000
+		if i > 0 {
+			expected = 1
+		}
+		if capacity := cap(element.(*typedColumn[*float64]).values); capacity != expected {
+			t.Errorf("FAILED: expected capacity %d for element %d, got %d\n", expected, i, capacity)
+		}
+	}
+}
+
+func TestFieldMetadata(t *testing.T) {
+	sdsType := sds.SdsType{
+		Properties: []sds.SdsTypeProperty{
+			{Id: "Timestamp", IsKey: true, SdsType: sds.SdsType{SdsTypeCode: "DateTime"}},
+			{Id: "Temp", Name: "Temperature", Description: "Inlet temperature", Uom: "degree Celsius", SdsType: sds.SdsType{SdsTypeCode: "Double"}},
+			{Id: "Flow", Uom: "barrel per day", SdsType: sds.SdsType{SdsTypeCode: "Double"}},
+			{Id: "Position", Name: "Arm position", SdsType: sds.SdsType{SdsTypeCode: "Object", Description: "Arm coordinates", Properties: []sds.SdsTypeProperty{
+				{Id: "X", Uom: "meter", SdsType: sds.SdsType{SdsTypeCode: "Double"}},
+			}}},
+			{Id: "Uptime", Uom: "hour", SdsType: sds.SdsType{SdsTypeCode: "TimeSpan"}},
+		},
+	}
+
+	frame, err := createDataFrameFromSdsData("test", sdsType, []byte(`[]`), "")
+	if err != nil {
+		t.Fatalf("FAILED: unexpected error %v\n", err)
+	}
+
+	expected := []*data.FieldConfig{
+		nil,
+		{DisplayNameFromDS: "Temperature", Description: "Inlet temperature", Unit: "celsius", Custom: map[string]interface{}{uomConfigKey: "degree Celsius"}},
+		{Unit: "suffix:barrel per day", Custom: map[string]interface{}{uomConfigKey: "barrel per day"}},
+		{DisplayNameFromDS: "Arm position.X", Unit: "lengthm", Custom: map[string]interface{}{uomConfigKey: "meter"}},
+		{Unit: "ms"},
+	}
+
+	for i, config := range expected {
+		if !reflect.DeepEqual(frame.Fields[i].Config, config) {
+			t.Errorf("FAILED: expected %v, got %v for %s\n", config, frame.Fields[i].Config, frame.Fields[i].Name)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package cds
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layouts tried, in order, when parsing SDS timestamps. SDS writes RFC 3339, but values
+// written by other clients may omit the offset or use a space as the separator.
+var sdsTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// Wraps a conversion so that null values, and values that cannot be converted, are kept
+// as nil pointers.
+func toNullable[T any](convert func(value interface{}) (T, error)) func(value interface{}) (*T, error) {
+	return func(value interface{}) (*T, error) {
+		if value == nil {
+			return nil, nil
+		}
+		valuePointer, err := convert(value)
+		if err != nil {
+			return nil, err
+		}
+		return &valuePointer, nil
+	}
+}
+
+func toTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case nil:
+		return time.Time{}, nil
+	case string:
+		return parseSdsTime(v)
+	default:
+		return time.Time{}, fmt.Errorf("unexpected timestamp %v", value)
+	}
+}
+
+// Parses a timestamp using the first matching layout. Timestamps without an offset are UTC.
+func parseSdsTime(text string) (time.Time, error) {
+	for _, layout := range sdsTimeLayouts {
+		if timestamp, err := time.Parse(layout, text); err == nil {
+			return timestamp, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unexpected timestamp %q", text)
+}
+
+// Converts a serialized .NET TimeSpan to milliseconds.
+func toTimeSpan(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case string:
+		duration, err := parseTimeSpan(v)
+		if err != nil {
+			return 0, err
+		}
+		return float64(duration) / float64(time.Millisecond), nil
+	default:
+		return 0, fmt.Errorf("unexpected TimeSpan %v", value)
+	}
+}
+
+// Converts a boolean, or a boolean serialized as a string or number, to a bool.
+func toBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	case string:
+		converted, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return false, fmt.Errorf("unexpected boolean %q", v)
+		}
+		return converted, nil
+	case json.Number:
+		number, err := v.Float64()
+		if err != nil {
+			return false, fmt.Errorf("unexpected boolean %q", v)
+		}
+		return number != 0, nil
+	default:
+		return false, fmt.Errorf("unexpected boolean %v", value)
+	}
+}
+
+// Converts a number, or a number serialized as a string such as "NaN" or "1.5", to T.
+// Integer conversions fail when the value is fractional or out of range for T.
+func toNumber[T number](value interface{}) (T, error) {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case json.Number:
+		text = string(v)
+	case string:
+		text = strings.TrimSpace(v)
+	case float64:
+		text = strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("unexpected number %v", value)
+	}
+
+	var converted T
+	switch any(converted).(type) {
+	case float32, float64:
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unexpected number %q", text)
+		}
+		return T(number), nil
+	case uint8, uint16, uint32, uint64:
+		number, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			// integers may be serialized with an exponent or a zero fraction
+			floatNumber, floatErr := strconv.ParseFloat(text, 64)
+			if floatErr != nil || floatNumber < 0 || floatNumber != math.Trunc(floatNumber) || floatNumber >= math.MaxUint64 {
+				return 0, fmt.Errorf("unexpected unsigned integer %q", text)
+			}
+			number = uint64(floatNumber)
+		}
+		converted = T(number)
+		if uint64(converted) != number {
+			return 0, fmt.Errorf("%q is out of range", text)
+		}
+		return converted, nil
+	default:
+		number, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			floatNumber, floatErr := strconv.ParseFloat(text, 64)
+			if floatErr != nil || floatNumber != math.Trunc(floatNumber) || floatNumber < math.MinInt64 || floatNumber >= math.MaxInt64 {
+				return 0, fmt.Errorf("unexpected integer %q", text)
+			}
+			number = int64(floatNumber)
+		}
+		converted = T(number)
+		if int64(converted) != number {
+			return 0, fmt.Errorf("%q is out of range", text)
+		}
+		return converted, nil
+	}
+}
+
+func toString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return string(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return "", fmt.Errorf("unexpected string %v", value)
+	}
+}
+
+// Parses a .NET TimeSpan in the constant format [-][d.]hh:mm:ss[.fffffff].
+func parseTimeSpan(text string) (time.Duration, error) {
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimPrefix(text, "-")
+
+	var days int64
+	if dot := strings.Index(text, "."); dot >= 0 && dot < strings.Index(text, ":") {
+		var err error
+		days, err = strconv.ParseInt(text[:dot], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid TimeSpan %q", text)
+		}
+		text = text[dot+1:]
+	}
+
+	parts := strings.Split(text, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid TimeSpan %q", text)
+	}
+
+	hours, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid TimeSpan %q", text)
+	}
+	minutes, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid TimeSpan %q", text)
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid TimeSpan %q", text)
+	}
+
+	duration := time.Duration(days)*24*time.Hour +
+		time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second))
+	if negative {
+		duration = -duration
+	}
+
+	return duration, nil
+}
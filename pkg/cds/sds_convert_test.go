@@ -0,0 +1,102 @@
+package cds
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aveva/connect-data-services/pkg/cds/sds"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestToNumber(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		convert  func(value interface{}) (float64, error)
+		expected float64
+		fails    bool
+	}{
+		{"number", json.Number("1.5"), toNumber[float64], 1.5, false},
+		{"string", "1.5", toNumber[float64], 1.5, false},
+		{"nan", "NaN", toNumber[float64], math.NaN(), false},
+		{"infinity", "-Infinity", toNumber[float64], math.Inf(-1), false},
+		{"int", json.Number("42"), func(value interface{}) (float64, error) { v, err := toNumber[int16](value); return float64(v), err }, 42, false},
+		{"exponent", json.Number("1e3"), func(value interface{}) (float64, error) { v, err := toNumber[int32](value); return float64(v), err }, 1000, false},
+		{"fraction", json.Number("1.5"), func(value interface{}) (float64, error) { v, err := toNumber[int32](value); return float64(v), err }, 0, true},
+		{"overflow", json.Number("300"), func(value interface{}) (float64, error) { v, err := toNumber[uint8](value); return float64(v), err }, 0, true},
+		{"negative", json.Number("-1"), func(value interface{}) (float64, error) { v, err := toNumber[uint16](value); return float64(v), err }, 0, true},
+		{"text", "abc", toNumber[float64], 0, true},
+		{"object", map[string]interface{}{}, toNumber[float64], 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := test.convert(test.value)
+			if test.fails != (err != nil) {
+				t.Errorf("FAILED: expected failure %v, got %v\n", test.fails, err)
+			}
+			if value != test.expected && !(math.IsNaN(value) && math.IsNaN(test.expected)) {
+				t.Errorf("FAILED: expected %v, got %v\n", test.expected, value)
+			}
+		})
+	}
+
+	// 64 bit integers keep their precision
+	if value, err := toNumber[uint64](json.Number("18446744073709551615")); err != nil || value != math.MaxUint64 {
+		t.Errorf("FAILED: expected %v, got %v (%v)\n", uint64(math.MaxUint64), value, err)
+	}
+}
+
+func TestParseSdsTime(t *testing.T) {
+	expected := time.Date(2022, 6, 4, 1, 2, 3, 123456700, time.UTC)
+	for _, text := range []string{"2022-06-04T01:02:03.1234567Z", "2022-06-04T01:02:03.1234567", "2022-06-04 01:02:03.1234567"} {
+		timestamp, err := parseSdsTime(text)
+		if err != nil || !timestamp.Equal(expected) {
+			t.Errorf("FAILED: expected %v for %s, got %v (%v)\n", expected, text, timestamp, err)
+		}
+	}
+
+	if _, err := parseSdsTime("June 4th"); err == nil {
+		t.Errorf("FAILED: expected an error for an invalid timestamp")
+	}
+}
+
+func TestConversionNotices(t *testing.T) {
+	sdsType := sds.SdsType{
+		Properties: []sds.SdsTypeProperty{
+			{Id: "Timestamp", SdsType: sds.SdsType{SdsTypeCode: "DateTime"}},
+			{Id: "Value", SdsType: sds.SdsType{SdsTypeCode: "NullableInt32"}},
+		},
+	}
+
+	body := []byte(`[
+		{"Timestamp": "2022-06-04T00:00:00Z", "Value": "7"},
+		{"Timestamp": "yesterday", "Value": {"unexpected": true}},
+		{"Timestamp": "2022-06-06T00:00:00Z", "Value": 1.5}
+	]`)
+
+	frame, err := createDataFrameFromSdsData("test", sdsType, body, "")
+	if err != nil {
+		t.Fatalf("FAILED: unexpected error %v\n", err)
+	}
+
+	seven := int32(7)
+	expected := []*data.Field{
+		data.NewField("Timestamp", nil, []time.Time{time.Date(2022, 6, 4, 0, 0, 0, 0, time.UTC), {}, time.Date(2022, 6, 6, 0, 0, 0, 0, time.UTC)}),
+		data.NewField("Value", nil, []*int32{&seven, nil, nil}),
+	}
+	if !reflect.DeepEqual(frame.Fields, expected) {
+		t.Errorf("FAILED: expected %v, got %v\n", expected, frame.Fields)
+	}
+
+	if frame.Meta == nil || len(frame.Meta.Notices) != 2 {
+		t.Fatalf("FAILED: expected 2 notices, got %v\n", frame.Meta)
+	}
+	if !strings.HasPrefix(frame.Meta.Notices[0].Text, "1 value of Timestamp could not") || !strings.HasPrefix(frame.Meta.Notices[1].Text, "2 values of Value could not") {
+		t.Errorf("FAILED: unexpected notices %v\n", frame.Meta.Notices)
+	}
+}
@@ -0,0 +1,110 @@
+package cds
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aveva/connect-data-services/pkg/cds/sds"
+)
+
+// Decodes a JSON array of SDS events into the columns of the event type. Properties
+// missing from an event are appended as null, and unknown properties are skipped.
+func decodeSdsEvents(body []byte, events *objectColumn) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("expected an array of events, got %v", token)
+	}
+
+	for decoder.More() {
+		if err = events.decode(decoder); err != nil {
+			return err
+		}
+	}
+
+	_, err = decoder.Token()
+	return err
+}
+
+// Decodes the next JSON value into a column.
+func decodeInto(decoder *json.Decoder, column sdsColumn) error {
+	if decodingColumn, ok := column.(decodingSdsColumn); ok {
+		return decodingColumn.decode(decoder)
+	}
+
+	value, err := decodeScalar(decoder)
+	if err != nil {
+		return err
+	}
+
+	column.append(value)
+	return nil
+}
+
+// Placeholder for an object or array found where a simple value was expected, so that
+// conversions can report it.
+type nestedValue json.Delim
+
+func (v nestedValue) String() string {
+	if json.Delim(v) == '[' {
+		return "array"
+	}
+	return "object"
+}
+
+// Reads the next JSON value. Objects and arrays are skipped and returned as a nestedValue.
+func decodeScalar(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if delim, ok := token.(json.Delim); ok {
+		return nestedValue(delim), skipNested(decoder, token)
+	}
+
+	return token, nil
+}
+
+// Skips the rest of an object or array when token is its opening delimiter.
+func skipNested(decoder *json.Decoder, token json.Token) error {
+	if token != json.Delim('{') && token != json.Delim('[') {
+		return nil
+	}
+
+	depth := 1
+	for depth > 0 {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := token.(json.Delim); ok {
+			if delim == '{' || delim == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+	}
+
+	return nil
+}
+
+// Estimates the number of events in a response by counting occurrences of the first
+// property, which is usually the key, so that columns can be allocated once.
+func estimateEventCount(sdsType sds.SdsType, body []byte) int {
+	if len(sdsType.Properties) == 0 {
+		return 0
+	}
+
+	return bytes.Count(body, []byte(`"`+sdsType.Properties[0].Id+`":`))
+}
@@ -0,0 +1,192 @@
+package cds
+
+import (
+	"strings"
+)
+
+// Grafana units of the SDS units of measure, keyed by the lower case SDS Uom id. Units of
+// measure that are not listed are shown as a suffix.
+var grafanaUnits = map[string]string{
+	// temperature
+	"degree celsius":    "celsius",
+	"degree fahrenheit": "fahrenheit",
+	"kelvin":            "kelvin",
+
+	// time
+	"millisecond": "ms",
+	"second":      "s",
+	"minute":      "m",
+	"hour":        "h",
+	"day":         "d",
+
+	// length
+	"millimeter": "lengthmm",
+	"meter":      "lengthm",
+	"kilometer":  "lengthkm",
+	"foot":       "lengthft",
+	"mile":       "lengthmi",
+
+	// mass
+	"milligram":  "massmg",
+	"gram":       "massg",
+	"kilogram":   "masskg",
+	"metric ton": "masst",
+
+	// volume
+	"milliliter":  "mlitre",
+	"liter":       "litre",
+	"cubic meter": "m3",
+	"us gallon":   "gallons",
+
+	// flow
+	"liter per minute":       "flowlpm",
+	"liter per hour":         "litreh",
+	"cubic meter per second": "flowcms",
+	"cubic foot per second":  "flowcfs",
+	"cubic foot per minute":  "flowcfm",
+	"us gallon per minute":   "flowgpm",
+
+	// velocity
+	"meter per second":      "velocityms",
+	"kilometer per hour":    "velocitykmh",
+	"mile per hour":         "velocitymph",
+	"knot":                  "velocityknot",
+	"revolution per minute": "rotrpm",
+
+	// pressure
+	"millibar":                    "pressurembar",
+	"bar":                         "pressurebar",
+	"hectopascal":                 "pressurehpa",
+	"kilopascal":                  "pressurekpa",
+	"pound-force per square inch": "pressurepsi",
+
+	// energy and power
+	"watt":          "watt",
+	"kilowatt":      "kwatt",
+	"megawatt":      "megwatt",
+	"watt hour":     "watth",
+	"kilowatt hour": "kwatth",
+	"joule":         "joule",
+	"volt":          "volt",
+	"kilovolt":      "kvolt",
+	"ampere":        "amp",
+	"hertz":         "hertz",
+
+	// ratios and angles
+	"percent":           "percent",
+	"parts per million": "ppm",
+	"degree":            "degree",
+	"radian":            "radian",
+}
+
+// Returns the Grafana unit for an SDS unit of measure.
+func grafanaUnit(uom string) string {
+	if uom == "" {
+		return ""
+	}
+
+	if unit, ok := grafanaUnits[strings.ToLower(uom)]; ok {
+		return unit
+	}
+
+	return "suffix:" + uom
+}
+
+// Conversion of a unit of measure to the base unit of its quantity class, where
+// base = value * factor + offset.
+type uomConversion struct {
+	quantity string
+	factor   float64
+	offset   float64
+}
+
+// Conversions of common SDS units of measure, keyed by the lower case SDS Uom id.
+var uomConversions = map[string]uomConversion{
+	// temperature, in kelvin
+	"kelvin":            {"temperature", 1, 0},
+	"degree celsius":    {"temperature", 1, 273.15},
+	"degree fahrenheit": {"temperature", 5.0 / 9, 459.67 * 5 / 9},
+	"degree rankine":    {"temperature", 5.0 / 9, 0},
+
+	// time, in seconds
+	"millisecond": {"time", 0.001, 0},
+	"second":      {"time", 1, 0},
+	"minute":      {"time", 60, 0},
+	"hour":        {"time", 3600, 0},
+	"day":         {"time", 86400, 0},
+
+	// length, in meters
+	"millimeter": {"length", 0.001, 0},
+	"centimeter": {"length", 0.01, 0},
+	"meter":      {"length", 1, 0},
+	"kilometer":  {"length", 1000, 0},
+	"inch":       {"length", 0.0254, 0},
+	"foot":       {"length", 0.3048, 0},
+	"mile":       {"length", 1609.344, 0},
+
+	// mass, in kilograms
+	"milligram":  {"mass", 0.000001, 0},
+	"gram":       {"mass", 0.001, 0},
+	"kilogram":   {"mass", 1, 0},
+	"metric ton": {"mass", 1000, 0},
+	"pound":      {"mass", 0.45359237, 0},
+
+	// volume, in cubic meters
+	"milliliter":  {"volume", 0.000001, 0},
+	"liter":       {"volume", 0.001, 0},
+	"cubic meter": {"volume", 1, 0},
+	"us gallon":   {"volume", 0.003785411784, 0},
+
+	// volume flow, in cubic meters per second
+	"liter per minute":       {"flow", 0.001 / 60, 0},
+	"liter per hour":         {"flow", 0.001 / 3600, 0},
+	"cubic meter per second": {"flow", 1, 0},
+	"cubic meter per hour":   {"flow", 1.0 / 3600, 0},
+	"cubic foot per second":  {"flow", 0.028316846592, 0},
+	"cubic foot per minute":  {"flow", 0.028316846592 / 60, 0},
+	"us gallon per minute":   {"flow", 0.003785411784 / 60, 0},
+
+	// velocity, in meters per second
+	"meter per second":   {"velocity", 1, 0},
+	"kilometer per hour": {"velocity", 1 / 3.6, 0},
+	"mile per hour":      {"velocity", 0.44704, 0},
+	"knot":               {"velocity", 1852.0 / 3600, 0},
+
+	// pressure, in pascals
+	"pascal":                      {"pressure", 1, 0},
+	"hectopascal":                 {"pressure", 100, 0},
+	"kilopascal":                  {"pressure", 1000, 0},
+	"megapascal":                  {"pressure", 1000000, 0},
+	"millibar":                    {"pressure", 100, 0},
+	"bar":                         {"pressure", 100000, 0},
+	"atmosphere":                  {"pressure", 101325, 0},
+	"pound-force per square inch": {"pressure", 6894.757293168, 0},
+
+	// energy, in joules
+	"joule":         {"energy", 1, 0},
+	"watt hour":     {"energy", 3600, 0},
+	"kilowatt hour": {"energy", 3600000, 0},
+
+	// power, in watts
+	"watt":     {"power", 1, 0},
+	"kilowatt": {"power", 1000, 0},
+	"megawatt": {"power", 1000000, 0},
+}
+
+// Returns a function converting values from one unit of measure to another, if both
+// units are known and measure the same quantity.
+func uomConverter(from string, to string) (func(float64) float64, bool) {
+	source, ok := uomConversions[strings.ToLower(from)]
+	if !ok {
+		return nil, false
+	}
+
+	target, ok := uomConversions[strings.ToLower(to)]
+	if !ok || source.quantity != target.quantity {
+		return nil, false
+	}
+
+	return func(value float64) float64 {
+		return (value*source.factor + source.offset - target.offset) / target.factor
+	}, true
+}
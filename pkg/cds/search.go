@@ -0,0 +1,284 @@
+package cds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Page size used when reading every page of search results.
+const maxSearchPageSize = 1000
+
+// Upper bound on the results read when reading every page, so that a search of a very
+// large namespace cannot exhaust memory.
+const maxSearchResults = 250000
+
+// Number of results SDS returns for a search without a count.
+const defaultSearchCount = 100
+
+// Upper bound on the pages read by a filtered search without all pages, so that a filter
+// matching few results cannot make every refresh read the whole collection.
+const maxFilteredSearchPages = 10
+
+// Upper bound on the streams whose metadata and tags are read by a search, since each
+// stream takes two requests.
+const maxMetadataStreams = 1000
+
+// Paging and ordering of a search. A count of zero uses the SDS default page size. Skip is
+// an offset into the results of the server, before any filter of the plugin is applied.
+// Reading the metadata and tags of the results takes two requests per result. Community
+// searches can be limited to the results contributed by one tenant. Queries that only use
+// part of the results can lower the results read for all pages with maxResults.
+type SearchOptions struct {
+	Skip            int
+	Count           int
+	OrderBy         string
+	AllPages        bool
+	IncludeMetadata bool
+	TenantId        string
+	maxResults      int
+}
+
+// Determines whether a community search result contributed by a tenant is included.
+func (o SearchOptions) includesTenant(tenantId string) bool {
+	return o.TenantId == "" || strings.EqualFold(o.TenantId, tenantId)
+}
+
+// Adds the paging and ordering parameters of a page to a search path.
+func (o SearchOptions) pagePath(path string, skip int, count int) string {
+	parameters := url.Values{}
+	if skip > 0 {
+		parameters.Set("skip", strconv.Itoa(skip))
+	}
+	if count > 0 {
+		parameters.Set("count", strconv.Itoa(count))
+	}
+	if o.OrderBy != "" {
+		parameters.Set("orderby", o.OrderBy)
+	}
+
+	if len(parameters) == 0 {
+		return path
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	return path + separator + parameters.Encode()
+}
+
+// Reads the results of a search. When all pages are requested, pages are read until a
+// page has fewer results than the page size, and the returned flag reports whether the
+// results were cut off at maxSearchResults.
+func searchPages[T any](d *CdsClient, token string, path string, headers map[string]string, options SearchOptions) ([]T, bool, error) {
+	return filteredSearchPages[T](d, token, path, headers, options, nil)
+}
+
+// Reads the results of a search that are kept by a filter, for filters the search API does
+// not support. Pages are read until count results are kept, or until every page is read
+// when all pages are requested, so that a filtered page has as many results as an
+// unfiltered one. Without all pages, at most maxFilteredSearchPages pages are read. The
+// returned flag is only set when results were kept, since there is nothing to refine
+// otherwise. A nil filter keeps every result.
+func filteredSearchPages[T any](d *CdsClient, token string, path string, headers map[string]string, options SearchOptions, keep func(T) bool) ([]T, bool, error) {
+	if !options.AllPages && keep == nil {
+		var results []T
+		err := searchPage(d, token, options.pagePath(path, options.Skip, options.Count), headers, &results)
+		return results, false, err
+	}
+
+	// without all pages, a filtered search returns one page of kept results
+	limit := maxSearchResults
+	if options.maxResults > 0 {
+		limit = min(limit, options.maxResults)
+	}
+	if !options.AllPages {
+		limit = options.Count
+		if limit <= 0 {
+			limit = defaultSearchCount
+		}
+	}
+
+	pageSize := options.Count
+	if pageSize <= 0 || pageSize > maxSearchPageSize {
+		pageSize = min(limit, maxSearchPageSize)
+	}
+
+	var results []T
+	read := 0
+	for pages, skip := 1, options.Skip; ; pages, skip = pages+1, skip+pageSize {
+		var page []T
+		err := searchPage(d, token, options.pagePath(path, skip, pageSize), headers, &page)
+		if err != nil {
+			return nil, false, err
+		}
+
+		read += len(page)
+		for _, result := range page {
+			if keep == nil || keep(result) {
+				results = append(results, result)
+			}
+		}
+		if len(results) >= limit {
+			if options.AllPages {
+				log.DefaultLogger.Warn("Search results truncated", "path", path, "results", len(results))
+			}
+			return results[:limit], options.AllPages, nil
+		}
+		if len(page) < pageSize {
+			return results, false, nil
+		}
+		if read >= maxSearchResults || (!options.AllPages && pages >= maxFilteredSearchPages) {
+			log.DefaultLogger.Warn("Search results truncated", "path", path, "read", read, "results", len(results))
+			return results, len(results) > 0, nil
+		}
+	}
+}
+
+// Reads one page of search results into v.
+func searchPage(d *CdsClient, token string, path string, headers map[string]string, v interface{}) error {
+	body, err := SdsRequest(d, token, path, headers)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		log.DefaultLogger.Warn("Error parsing json", err.Error())
+		log.DefaultLogger.Warn(fmt.Sprint(string(body)))
+		return err
+	}
+
+	return nil
+}
+
+// Columns of a stream search result frame.
+type streamColumns struct {
+	ids             []string
+	names           []string
+	typeIds         []string
+	descriptions    []string
+	includeMetadata bool
+	metadataLimited bool
+	tags            []string
+	metadata        []json.RawMessage
+	community       bool
+	tenantIds       []string
+	tenantNames     []string
+	namespaceIds    []string
+}
+
+func newStreamColumns(capacity int, includeMetadata bool) *streamColumns {
+	return &streamColumns{
+		ids:             make([]string, 0, capacity),
+		names:           make([]string, 0, capacity),
+		typeIds:         make([]string, 0, capacity),
+		descriptions:    make([]string, 0, capacity),
+		includeMetadata: includeMetadata,
+	}
+}
+
+// Creates the columns of a community stream search, which include the contributing
+// tenant and namespace of each stream.
+func newCommunityStreamColumns(capacity int, includeMetadata bool) *streamColumns {
+	c := newStreamColumns(capacity, includeMetadata)
+	c.community = true
+	c.tenantIds = make([]string, 0, capacity)
+	c.tenantNames = make([]string, 0, capacity)
+	c.namespaceIds = make([]string, 0, capacity)
+	return c
+}
+
+func (c *streamColumns) add(id string, name string, typeId string, description string) {
+	c.ids = append(c.ids, id)
+	c.names = append(c.names, name)
+	c.typeIds = append(c.typeIds, typeId)
+	c.descriptions = append(c.descriptions, description)
+}
+
+// Adds the contributing tenant and namespace of the last added community stream.
+func (c *streamColumns) addCommunity(tenantId string, tenantName string, namespaceId string) {
+	c.tenantIds = append(c.tenantIds, tenantId)
+	c.tenantNames = append(c.tenantNames, tenantName)
+	c.namespaceIds = append(c.namespaceIds, namespaceId)
+}
+
+// Reads the metadata and tags of the last added stream, unless maxMetadataStreams streams
+// already have them, in which case the stream gets empty metadata and tags.
+func (c *streamColumns) readMetadata(read func() (map[string]string, []string)) {
+	if len(c.tags) >= maxMetadataStreams {
+		c.metadataLimited = true
+		c.addMetadata(nil, nil)
+		return
+	}
+
+	c.addMetadata(read())
+}
+
+// Adds the metadata and tags of the last added stream, with the metadata as a JSON object.
+func (c *streamColumns) addMetadata(metadata map[string]string, tags []string) {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+
+	// a map of strings always marshals
+	body, _ := json.Marshal(metadata)
+	c.metadata = append(c.metadata, body)
+	c.tags = append(c.tags, strings.Join(tags, ","))
+}
+
+// Creates the search result frame, with a notice when the results were truncated.
+func (c *streamColumns) frame(truncated bool) *data.Frame {
+	frame := data.NewFrame("response",
+		data.NewField("Id", nil, c.ids),
+		data.NewField("Name", nil, c.names),
+		data.NewField("TypeId", nil, c.typeIds),
+		data.NewField("Description", nil, c.descriptions),
+	)
+
+	if c.community {
+		frame.Fields = append(frame.Fields,
+			data.NewField("TenantId", nil, c.tenantIds),
+			data.NewField("TenantName", nil, c.tenantNames),
+			data.NewField("NamespaceId", nil, c.namespaceIds),
+		)
+	}
+
+	if c.includeMetadata {
+		frame.Fields = append(frame.Fields,
+			data.NewField("Tags", nil, c.tags),
+			data.NewField("Metadata", nil, c.metadata),
+		)
+	}
+
+	var notices []data.Notice
+	if truncated {
+		notices = append(notices, truncatedNotice(len(c.ids)))
+	}
+	if c.metadataLimited {
+		notices = append(notices, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Metadata and tags are only read for the first %d streams, refine the search to see them for other streams", maxMetadataStreams),
+		})
+	}
+	if len(notices) > 0 {
+		frame.Meta = &data.FrameMeta{Notices: notices}
+	}
+
+	return frame
+}
+
+// Describes search results that were cut off at maxSearchResults.
+func truncatedNotice(results int) data.Notice {
+	return data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text:     fmt.Sprintf("Only the first %d results are shown, refine the search to see other results", results),
+	}
+}
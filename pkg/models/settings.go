@@ -8,19 +8,66 @@ import (
 )
 
 type CdsSettings struct {
-	Resource      string             `json:"resource"`
-	ApiVersion    string             `json:"apiVersion"`
-	TenantId      string             `json:"tenantId"`
-	NamespaceId   string             `json:"namespaceId"`
-	UseCommunity  bool               `json:"useCommunity"`
-	CommunityId   string             `json:"communityId"`
-	ClientId      string             `json:"clientId"`
-	OauthPassThru bool               `json:"oauthPassThru"`
-	Secrets       *SecretCdsSettings `json:"-"`
+	Type                      string             `json:"type"`
+	EdsHost                   string             `json:"edsHost"`
+	EdsPort                   string             `json:"edsPort"`
+	Resource                  string             `json:"resource"`
+	ApiVersion                string             `json:"apiVersion"`
+	TenantId                  string             `json:"tenantId"`
+	NamespaceId               string             `json:"namespaceId"`
+	NamespaceIds              []string           `json:"namespaceIds"`
+	UseCommunity              bool               `json:"useCommunity"`
+	CommunityId               string             `json:"communityId"`
+	ClientId                  string             `json:"clientId"`
+	OauthPassThru             bool               `json:"oauthPassThru"`
+	ClientCredentialsFallback bool               `json:"clientCredentialsFallback"`
+	ClientAuthMethod          string             `json:"clientAuthMethod"`
+	Scopes                    string             `json:"scopes"`
+	Audience                  string             `json:"audience"`
+	CacheTtl                  int                `json:"cacheTtl"`
+	CacheSize                 int                `json:"cacheSize"`
+	MetadataCacheTtl          int                `json:"metadataCacheTtl"`
+	IncrementalQueries        bool               `json:"incrementalQueries"`
+	Secrets                   *SecretCdsSettings `json:"-"`
+}
+
+// Number of query results kept by the query cache when no size is configured.
+const DefaultCacheSize = 100
+
+// Seconds stream and type metadata is reused when no metadata cache ttl is configured.
+const DefaultMetadataCacheTtl = 300
+
+// Type of a data source that reads from Edge Data Store instead of CONNECT data services.
+const EdsType = "EDS"
+
+// Host and port of Edge Data Store when none are configured.
+const DefaultEdsHost = "localhost"
+const DefaultEdsPort = "5590"
+
+// Determines whether the data source reads from Edge Data Store.
+func (s CdsSettings) IsEds() bool {
+	return s.Type == EdsType
+}
+
+// Determines whether queries may read from a namespace. Besides the namespace of the data
+// source, queries may read from the additional namespaces of the settings.
+func (s CdsSettings) AllowsNamespace(namespaceId string) bool {
+	if namespaceId == s.NamespaceId {
+		return true
+	}
+
+	for _, allowed := range s.NamespaceIds {
+		if namespaceId == allowed {
+			return true
+		}
+	}
+
+	return false
 }
 
 type SecretCdsSettings struct {
-	ClientSecret string
+	ClientSecret      string
+	ClientCertificate string
 }
 
 func LoadPluginSettings(source backend.DataSourceInstanceSettings) (*CdsSettings, error) {
@@ -30,6 +77,29 @@ func LoadPluginSettings(source backend.DataSourceInstanceSettings) (*CdsSettings
 		return nil, fmt.Errorf("could not unmarshal CdsSettings json: %w", err)
 	}
 
+	if settings.CacheSize <= 0 {
+		settings.CacheSize = DefaultCacheSize
+	}
+
+	if settings.MetadataCacheTtl == 0 {
+		settings.MetadataCacheTtl = DefaultMetadataCacheTtl
+	}
+
+	// Edge Data Store has a single default tenant, no communities and no authentication
+	if settings.IsEds() {
+		if settings.EdsHost == "" {
+			settings.EdsHost = DefaultEdsHost
+		}
+		if settings.EdsPort == "" {
+			settings.EdsPort = DefaultEdsPort
+		}
+		if settings.NamespaceId == "" {
+			settings.NamespaceId = "default"
+		}
+		settings.UseCommunity = false
+		settings.OauthPassThru = false
+	}
+
 	settings.Secrets = loadSecretPluginSettings(source.DecryptedSecureJSONData)
 
 	return &settings, nil
@@ -37,6 +107,7 @@ func LoadPluginSettings(source backend.DataSourceInstanceSettings) (*CdsSettings
 
 func loadSecretPluginSettings(source map[string]string) *SecretCdsSettings {
 	return &SecretCdsSettings{
-		ClientSecret: source["clientSecret"],
+		ClientSecret:      source["clientSecret"],
+		ClientCertificate: source["clientCertificate"],
 	}
 }
@@ -6,7 +6,7 @@ import {
   onUpdateDatasourceJsonDataOptionChecked,
   onUpdateDatasourceJsonDataOptionSelect,
 } from '@grafana/data';
-import { Select, InlineSwitch, InlineField, Input, InlineFieldRow } from '@grafana/ui';
+import { Select, InlineSwitch, InlineField, Input, InlineFieldRow, SecretTextArea, TagsInput } from '@grafana/ui';
 import { SdsDataSourceOptions, SdsDataSourceType, SdsDataSourceSecureOptions } from '../types';
 
 interface Props extends DataSourcePluginOptionsEditorProps<SdsDataSourceOptions, SdsDataSourceSecureOptions> {}
@@ -27,6 +27,12 @@ export const ConfigEditor = (props: Props) => {
     { value: 'diagnostics', label: 'diagnostics' },
   ];
 
+  const authMethodOptions = [
+    { value: 'client_secret_post', label: 'Client secret (form)' },
+    { value: 'client_secret_basic', label: 'Client secret (basic)' },
+    { value: 'private_key_jwt', label: 'Private key JWT' },
+  ];
+
   const warningStyle = {
     color: 'orange',
     alignSelf: 'center',
@@ -46,6 +52,40 @@ export const ConfigEditor = (props: Props) => {
     onOptionsChange({ ...options, secureJsonData, secureJsonFields });
   };
 
+  const onResetClientCertificate = () => {
+    const { onOptionsChange, options } = props;
+    const secureJsonData = {
+      ...options.secureJsonData,
+      clientCertificate: '',
+    };
+    const secureJsonFields = {
+      ...options.secureJsonFields,
+      clientCertificate: false,
+    };
+    onOptionsChange({ ...options, secureJsonData, secureJsonFields });
+  };
+
+  const onClientCertificateChange = (event: React.ChangeEvent<HTMLTextAreaElement>) => {
+    const { onOptionsChange, options } = props;
+    const secureJsonData = {
+      ...options.secureJsonData,
+      clientCertificate: event.currentTarget.value,
+    };
+    onOptionsChange({ ...options, secureJsonData });
+  };
+
+  const onNamespaceIdsChange = (namespaceIds: string[]) => {
+    const { onOptionsChange, options } = props;
+    onOptionsChange({ ...options, jsonData: { ...options.jsonData, namespaceIds } });
+  };
+
+  const onNumberOptionChange =
+    (key: 'cacheTtl' | 'cacheSize' | 'metadataCacheTtl') => (event: React.FormEvent<HTMLInputElement>) => {
+      const { onOptionsChange, options } = props;
+      const value = parseInt(event.currentTarget.value, 10);
+      onOptionsChange({ ...options, jsonData: { ...options.jsonData, [key]: isNaN(value) ? undefined : value } });
+    };
+
   const { options } = props;
   const { jsonData, secureJsonData } = options;
 
@@ -53,6 +93,9 @@ export const ConfigEditor = (props: Props) => {
   if (!jsonData.type) {
     jsonData.type = SdsDataSourceType.ADH;
   }
+  if (!jsonData.edsHost) {
+    jsonData.edsHost = 'localhost';
+  }
   if (!jsonData.edsPort) {
     jsonData.edsPort = '5590';
   }
@@ -62,6 +105,9 @@ export const ConfigEditor = (props: Props) => {
   if (!jsonData.apiVersion) {
     jsonData.apiVersion = 'v1';
   }
+  if (!jsonData.clientAuthMethod) {
+    jsonData.clientAuthMethod = 'client_secret_post';
+  }
   if (jsonData.oauthPassThru == null) {
     jsonData.oauthPassThru = false;
   }
@@ -95,6 +141,21 @@ export const ConfigEditor = (props: Props) => {
       {jsonData.type === SdsDataSourceType.EDS ? (
         <div className="gf-form-group">
           <h3 className="page-heading">Edge Data Store</h3>
+          <div>
+            <InlineField
+              label="Host"
+              tooltip="The host name of Edge Data Store, as reached from the Grafana server, without a port or path"
+              labelWidth={20}
+            >
+              <Input
+                required={true}
+                placeholder="localhost"
+                width={40}
+                onChange={onUpdateDatasourceJsonDataOption(props, 'edsHost')}
+                value={jsonData.edsHost || ''}
+              />
+            </InlineField>
+          </div>
           <div>
             <InlineField label="Port" tooltip="The port number used by Edge Data Store" labelWidth={20}>
               <Input
@@ -181,6 +242,20 @@ export const ConfigEditor = (props: Props) => {
               />
             </InlineField>
           )}
+          {!jsonData.useCommunity && (
+            <InlineField
+              label="Other Namespaces"
+              tooltip="Additional Namespaces of your CONNECT data services tenant that queries may select"
+              labelWidth={20}
+            >
+              <TagsInput
+                width={40}
+                placeholder="New namespace (enter key to add)"
+                tags={jsonData.namespaceIds || []}
+                onChange={onNamespaceIdsChange}
+              />
+            </InlineField>
+          )}
           <InlineFieldRow>
             <InlineField label="Use OAuth token" tooltip="Switch to toggle authentication modes" labelWidth={20}>
               <InlineSwitch
@@ -194,7 +269,21 @@ export const ConfigEditor = (props: Props) => {
               </div>
             )}
           </InlineFieldRow>
-          {!jsonData.oauthPassThru && (
+          {jsonData.oauthPassThru && (
+            <InlineFieldRow>
+              <InlineField
+                label="Client fallback"
+                tooltip="Use the Client Credentials client for requests without a signed in user, such as alerting"
+                labelWidth={20}
+              >
+                <InlineSwitch
+                  onChange={onUpdateDatasourceJsonDataOptionChecked(props, 'clientCredentialsFallback')}
+                  value={jsonData.clientCredentialsFallback}
+                />
+              </InlineField>
+            </InlineFieldRow>
+          )}
+          {(!jsonData.oauthPassThru || jsonData.clientCredentialsFallback) && (
             <InlineField
               label="Client ID"
               tooltip="The ID of the Client Credentials client to authenticate against your Cds tenant"
@@ -208,25 +297,138 @@ export const ConfigEditor = (props: Props) => {
               />
             </InlineField>
           )}
-          {!jsonData.oauthPassThru && (
+          {(!jsonData.oauthPassThru || jsonData.clientCredentialsFallback) && (
             <InlineField
-              label="Client Secret"
-              tooltip="The secret for the specified Client Credentials client"
+              label="Authentication"
+              tooltip="How the Client Credentials client authenticates when requesting a token"
+              labelWidth={20}
+            >
+              <Select
+                width={40}
+                options={authMethodOptions}
+                onChange={onUpdateDatasourceJsonDataOptionSelect(props, 'clientAuthMethod')}
+                value={authMethodOptions.find((x) => x.value === jsonData.clientAuthMethod)}
+              />
+            </InlineField>
+          )}
+          {(!jsonData.oauthPassThru || jsonData.clientCredentialsFallback) &&
+            jsonData.clientAuthMethod !== 'private_key_jwt' && (
+              <InlineField
+                label="Client Secret"
+                tooltip="The secret for the specified Client Credentials client"
+                labelWidth={20}
+              >
+                <Input
+                  required={true}
+                  type="password"
+                  placeholder="Enter a Client secret..."
+                  width={40}
+                  onChange={onUpdateDatasourceSecureJsonDataOption(props, 'clientSecret')}
+                  onReset={onResetClientSecret}
+                  value={secureJsonData?.clientSecret || ''}
+                />
+              </InlineField>
+            )}
+          {(!jsonData.oauthPassThru || jsonData.clientCredentialsFallback) &&
+            jsonData.clientAuthMethod === 'private_key_jwt' && (
+              <InlineField
+                label="Client Certificate"
+                tooltip="The PEM encoded certificate of the client and its RSA private key, used to sign client assertions"
+                labelWidth={20}
+              >
+                <SecretTextArea
+                  cols={60}
+                  rows={8}
+                  placeholder="-----BEGIN CERTIFICATE-----"
+                  isConfigured={options.secureJsonFields?.clientCertificate || false}
+                  onChange={onClientCertificateChange}
+                  onReset={onResetClientCertificate}
+                />
+              </InlineField>
+            )}
+          {(!jsonData.oauthPassThru || jsonData.clientCredentialsFallback) && (
+            <InlineField
+              label="Scopes"
+              tooltip="Space separated scopes to request, if the client requires any"
               labelWidth={20}
             >
               <Input
-                required={true}
-                type="password"
-                placeholder="Enter a Client secret..."
+                placeholder="ocsapi"
                 width={40}
-                onChange={onUpdateDatasourceSecureJsonDataOption(props, 'clientSecret')}
-                onReset={onResetClientSecret}
-                value={secureJsonData?.clientSecret || ''}
+                onChange={onUpdateDatasourceJsonDataOption(props, 'scopes')}
+                value={jsonData.scopes || ''}
+              />
+            </InlineField>
+          )}
+          {(!jsonData.oauthPassThru || jsonData.clientCredentialsFallback) && (
+            <InlineField
+              label="Audience"
+              tooltip="The audience to request the token for, if the client requires one"
+              labelWidth={20}
+            >
+              <Input
+                width={40}
+                onChange={onUpdateDatasourceJsonDataOption(props, 'audience')}
+                value={jsonData.audience || ''}
               />
             </InlineField>
           )}
         </div>
       )}
+      <div className="gf-form-group">
+        <h3 className="page-heading">Caching</h3>
+        <InlineField
+          label="Query cache TTL"
+          tooltip="How long, in seconds, the results of stream data queries over past time ranges are kept. Leave empty or 0 to disable the query cache."
+          labelWidth={20}
+        >
+          <Input
+            type="number"
+            placeholder="0"
+            width={40}
+            onChange={onNumberOptionChange('cacheTtl')}
+            value={jsonData.cacheTtl ?? ''}
+          />
+        </InlineField>
+        <InlineField
+          label="Cache size"
+          tooltip="The maximum number of query results, and of streams of incremental queries, kept in memory"
+          labelWidth={20}
+        >
+          <Input
+            type="number"
+            placeholder="100"
+            width={40}
+            onChange={onNumberOptionChange('cacheSize')}
+            value={jsonData.cacheSize ?? ''}
+          />
+        </InlineField>
+        <InlineField
+          label="Metadata cache TTL"
+          tooltip="How long, in seconds, stream and type definitions are reused before being revalidated. A negative value disables the metadata cache."
+          labelWidth={20}
+        >
+          <Input
+            type="number"
+            placeholder="300"
+            width={40}
+            onChange={onNumberOptionChange('metadataCacheTtl')}
+            value={jsonData.metadataCacheTtl ?? ''}
+          />
+        </InlineField>
+        <InlineFieldRow>
+          <InlineField
+            label="Incremental queries"
+            tooltip="For ranges ending now, only request the data after the data previously fetched for a stream"
+            labelWidth={20}
+          >
+            <InlineSwitch
+              onChange={onUpdateDatasourceJsonDataOptionChecked(props, 'incrementalQueries')}
+              value={jsonData.incrementalQueries}
+            />
+          </InlineField>
+        </InlineFieldRow>
+      </div>
     </div>
   );
 };
@@ -1,14 +1,29 @@
 import React from 'react';
-import { AsyncSelect, InlineFormLabel } from '@grafana/ui';
+import { AsyncSelect, InlineFormLabel, InlineSwitch, Input, Select } from '@grafana/ui';
 import { QueryEditorProps, SelectableValue } from '@grafana/data';
 import { DataSource } from '../datasource';
-import { defaultQuery, SdsDataSourceOptions, SdsQuery } from '../types';
+import { defaultQuery, SdsDataSourceOptions, SdsDataSourceType, SdsQuery } from '../types';
 import { debounce } from '../debounce';
 
 type Props = QueryEditorProps<DataSource, SdsQuery, SdsDataSourceOptions>;
 
+const collections: Array<SelectableValue<string>> = [
+  { label: 'Streams', value: 'streams', description: 'Stream data, or a stream search without a stream' },
+  { label: 'Stream type', value: 'streams/{id}/type', description: 'Properties of the type of the stream' },
+  { label: 'Types', value: 'types', description: 'Properties of the types matching the search' },
+  { label: 'Stream views', value: 'streamviews', description: 'Stream views matching the search' },
+  { label: 'Data views', value: 'dataviews', description: 'Interpolated data of a data view, or the data views without an id' },
+  { label: 'Assets', value: 'assets', description: 'Data of the streams of an asset, or the assets matching the search without an id' },
+  { label: 'Asset status', value: 'assetstatus', description: 'Status and metadata of an asset, or of the assets matching the search' },
+];
+
 export function QueryEditor({ query, datasource, onChange }: Props) {
-  const combinedQuery = { ...defaultQuery, ...query };
+  // Edge Data Store queries return booleans as numbers by default
+  const combinedQuery = {
+    ...defaultQuery,
+    booleansAsNumbers: datasource.type === SdsDataSourceType.EDS,
+    ...query,
+  };
 
   const selectStream: SelectableValue<string> = { label: combinedQuery.name, value: combinedQuery.id };
   const [defaultOptions, setDefaultOptions] = React.useState<boolean | Array<SelectableValue<string>>>(true);
@@ -17,23 +32,219 @@ export function QueryEditor({ query, datasource, onChange }: Props) {
     onChange({ ...combinedQuery, id: value.value || '', name: value.label || '' });
   };
 
+  const onCollectionChange = (value: SelectableValue<string>) => {
+    onChange({ ...combinedQuery, collection: value.value || 'streams' });
+  };
+
+  const onQueryTextChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...combinedQuery, queryText: event.currentTarget.value });
+  };
+
+  const onIdChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...combinedQuery, id: event.currentTarget.value, name: event.currentTarget.value });
+  };
+
+  const onNamespaceIdChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...combinedQuery, namespaceId: event.currentTarget.value });
+  };
+
+  const onTenantIdChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...combinedQuery, tenantId: event.currentTarget.value });
+  };
+
+  const onLastValueChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...combinedQuery, lastValue: event.currentTarget.checked });
+  };
+
+  const onIncludeLabelsChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...combinedQuery, includeLabels: event.currentTarget.checked });
+  };
+
+  const onIntervalChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...combinedQuery, interval: event.currentTarget.value });
+  };
+
+  const onBooleansAsNumbersChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...combinedQuery, booleansAsNumbers: event.currentTarget.checked });
+  };
+
+  const onStartIndexChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...combinedQuery, startIndex: event.currentTarget.value });
+  };
+
+  const onEndIndexChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...combinedQuery, endIndex: event.currentTarget.value });
+  };
+
+  const onSkipChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...combinedQuery, skip: parseInt(event.currentTarget.value, 10) || 0 });
+  };
+
+  const onCountChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...combinedQuery, count: parseInt(event.currentTarget.value, 10) || 0 });
+  };
+
+  const onOrderByChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...combinedQuery, orderBy: event.currentTarget.value });
+  };
+
+  const onAllPagesChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...combinedQuery, allPages: event.currentTarget.checked });
+  };
+
+  const onIncludeMetadataChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...combinedQuery, includeMetadata: event.currentTarget.checked });
+  };
+
+  const onTargetUomsChange = (event: React.FormEvent<HTMLInputElement>) => {
+    const targetUoms = event.currentTarget.value
+      .split(',')
+      .map((uom) => uom.trim())
+      .filter((uom) => uom !== '');
+    onChange({ ...combinedQuery, targetUoms });
+  };
+
+  // collections without an id search for streams, types, assets and other catalog items
+  const isSearch = !combinedQuery.id || combinedQuery.collection === 'types' || combinedQuery.collection === 'streamviews';
+
   const debouncedGetStreams = debounce(
-    (inputvalue: string) => datasource.getStreams(inputvalue, setDefaultOptions),
+    (inputvalue: string) => datasource.getStreams(inputvalue, setDefaultOptions, combinedQuery.namespaceId),
     1000
   );
 
 return (
     <div className="gf-form">
-      <InlineFormLabel width={8}>Stream</InlineFormLabel>
-      <AsyncSelect
-        defaultOptions={defaultOptions}
-        width={50}
-        loadOptions={debouncedGetStreams}
-        value={selectStream}
-        onChange={onSelectedStream}
-        placeholder="Select Stream"
-        loadingMessage={'Loading streams...'}
-        noOptionsMessage={'No streams found'}
+      {!datasource.useCommunity && (
+        <>
+          <InlineFormLabel
+            width={8}
+            tooltip="Namespace to read from instead of the namespace of the data source. It must be one of the namespaces allowed by the data source, and can use dashboard variables."
+          >
+            Namespace
+          </InlineFormLabel>
+          <Input
+            width={20}
+            placeholder="Data source namespace"
+            defaultValue={combinedQuery.namespaceId}
+            onBlur={onNamespaceIdChange}
+          />
+        </>
+      )}
+      <InlineFormLabel width={8}>Collection</InlineFormLabel>
+      <Select width={20} options={collections} value={combinedQuery.collection} onChange={onCollectionChange} />
+      {datasource.useCommunity && ['streams', 'assets', 'dataviews'].includes(combinedQuery.collection ?? '') && (
+        <>
+          <InlineFormLabel width={8} tooltip="Only search the streams, assets or data views contributed by this tenant">
+            Tenant
+          </InlineFormLabel>
+          <Input width={20} placeholder="All tenants" defaultValue={combinedQuery.tenantId} onBlur={onTenantIdChange} />
+        </>
+      )}
+      {(combinedQuery.collection === 'types' ||
+        combinedQuery.collection === 'streamviews' ||
+        ((combinedQuery.collection === 'assets' || combinedQuery.collection === 'assetstatus') && !combinedQuery.id)) && (
+        <Input width={30} placeholder="Search" defaultValue={combinedQuery.queryText} onBlur={onQueryTextChange} />
+      )}
+      {combinedQuery.collection === 'assetstatus' ? (
+        <>
+          <InlineFormLabel width={8}>Asset</InlineFormLabel>
+          <Input width={30} placeholder="Asset id, or all matching assets" defaultValue={combinedQuery.id} onBlur={onIdChange} />
+        </>
+      ) : combinedQuery.collection === 'assets' ? (
+        <>
+          <InlineFormLabel width={8}>Asset</InlineFormLabel>
+          <Input width={30} placeholder="Asset id" defaultValue={combinedQuery.id} onBlur={onIdChange} />
+          <InlineFormLabel width={8} tooltip="Read the last value of each stream instead of the dashboard time range">
+            Last value
+          </InlineFormLabel>
+          <InlineSwitch value={combinedQuery.lastValue} onChange={onLastValueChange} />
+        </>
+      ) : combinedQuery.collection === 'dataviews' ? (
+        <>
+          <InlineFormLabel width={8}>Data view</InlineFormLabel>
+          <Input width={30} placeholder="Data view id" defaultValue={combinedQuery.id} onBlur={onIdChange} />
+          <InlineFormLabel width={8} tooltip="Interpolation interval as a TimeSpan, such as 00:05:00. Defaults to the panel interval.">
+            Interval
+          </InlineFormLabel>
+          <Input width={15} placeholder="00:05:00" defaultValue={combinedQuery.interval} onBlur={onIntervalChange} />
+        </>
+      ) : (
+        <>
+          <InlineFormLabel width={8}>Stream</InlineFormLabel>
+          <AsyncSelect
+            defaultOptions={defaultOptions}
+            width={50}
+            loadOptions={debouncedGetStreams}
+            value={selectStream}
+            onChange={onSelectedStream}
+            placeholder="Select Stream"
+            loadingMessage={'Loading streams...'}
+            noOptionsMessage={'No streams found'}
+          />
+        </>
+      )}
+      {(combinedQuery.collection === 'streams' || combinedQuery.collection === 'assets') && (
+        <>
+          <InlineFormLabel
+            width={8}
+            tooltip="Add the metadata and tags of the streams as labels of the value fields, which takes two extra requests per stream"
+          >
+            Labels
+          </InlineFormLabel>
+          <InlineSwitch value={combinedQuery.includeLabels} onChange={onIncludeLabelsChange} />
+        </>
+      )}
+      {isSearch && !combinedQuery.collection?.includes('{id}') && (
+        <>
+          <InlineFormLabel width={8} tooltip="Number of results to skip, and number of results per page">
+            Page
+          </InlineFormLabel>
+          <Input width={10} type="number" placeholder="Skip" defaultValue={combinedQuery.skip || ''} onBlur={onSkipChange} />
+          <Input width={10} type="number" placeholder="Count" defaultValue={combinedQuery.count || ''} onBlur={onCountChange} />
+          <InlineFormLabel width={8} tooltip="Order of the results, such as Name desc">
+            Order by
+          </InlineFormLabel>
+          <Input width={15} placeholder="Name" defaultValue={combinedQuery.orderBy} onBlur={onOrderByChange} />
+          <InlineFormLabel width={8} tooltip="Read every page of results, up to 250,000 results">
+            All pages
+          </InlineFormLabel>
+          <InlineSwitch value={combinedQuery.allPages} onChange={onAllPagesChange} />
+          {combinedQuery.collection === 'streams' && (
+            <>
+              <InlineFormLabel
+                width={8}
+                tooltip="Add Tags and Metadata columns, which takes two extra requests per stream, for up to 1,000 streams"
+              >
+                Metadata
+              </InlineFormLabel>
+              <InlineSwitch value={combinedQuery.includeMetadata} onChange={onIncludeMetadataChange} />
+            </>
+          )}
+        </>
+      )}
+      <InlineFormLabel width={10} tooltip="Render boolean values as 1 and 0">
+        Booleans as numbers
+      </InlineFormLabel>
+      <InlineSwitch value={combinedQuery.booleansAsNumbers} onChange={onBooleansAsNumbersChange} />
+      <InlineFormLabel
+        width={8}
+        tooltip="Index range to read instead of the dashboard time range, for streams not indexed by time. Separate compound index values with |, and use $__from or $__to for the dashboard time range."
+      >
+        Index range
+      </InlineFormLabel>
+      <Input width={20} placeholder="Start index" value={combinedQuery.startIndex} onChange={onStartIndexChange} />
+      <Input width={20} placeholder="End index" value={combinedQuery.endIndex} onChange={onEndIndexChange} />
+      <InlineFormLabel
+        width={8}
+        tooltip="Comma separated SDS units of measure to convert values to, such as degree Fahrenheit. Values are converted when their unit of measure measures the same quantity."
+      >
+        Units
+      </InlineFormLabel>
+      <Input
+        width={30}
+        placeholder="degree Celsius, bar"
+        defaultValue={combinedQuery.targetUoms?.join(', ')}
+        onBlur={onTargetUomsChange}
       />
     </div>
   );
@@ -1,21 +1,25 @@
 import { DataQueryRequest, DataSourceInstanceSettings, FieldType, MutableDataFrame } from '@grafana/data';
-import { SdsDataSourceOptions, SdsDataSourceType, SdsQuery } from 'types';
+import { defaultQuery, SdsDataSourceOptions, SdsDataSourceType, SdsQuery } from 'types';
 import { DataSource } from 'datasource';
 import { Observable } from 'rxjs';
 
+// the template service also replaces the built in $__from variable with epoch milliseconds
+const mockVariables: Record<string, string> = { stream: 'Id1', namespace: 'NAMESPACE2', __from: '1654300800000' };
+
 jest.mock('@grafana/runtime', () => {
   const original = jest.requireActual('@grafana/runtime');
   return {
     ...original,
     getTemplateSrv: () => ({
       getVariables: () => [],
-      replace: (s: string) => s,
+      replace: (s: string) => s.replace(/\$(\w+)/g, (match, name) => mockVariables[name] ?? match),
     }),
   };
 });
 
 describe('DataSource', () => {
   const url = 'URL';
+  const edsHost = 'HOST';
   const edsPort = 'PORT';
   const resource = 'URL';
   const apiVersion = 'VERSION';
@@ -35,6 +39,7 @@ describe('DataSource', () => {
     meta: null as any,
     jsonData: {
       type: SdsDataSourceType.ADH,
+      edsHost: edsHost,
       edsPort: edsPort,
       resource: resource,
       apiVersion: apiVersion,
@@ -52,77 +57,75 @@ describe('DataSource', () => {
     it('should use passed in data source information', () => {
       const datasource = new DataSource(adhSettings);
       expect(datasource.type).toEqual(SdsDataSourceType.ADH);
-      expect(datasource.edsPort).toEqual(edsPort);
+      expect(datasource.useCommunity).toEqual(useCommunity);
     });
   });
 
-  describe('queryEDS', () => {
-    it('should query with the expected parameters', (done) => {
-      const options = {
-        range: {
-          from: {
-            utc: () => ({
-              format: () => 'FROM',
-            }),
-          },
-          to: {
-            utc: () => ({
-              format: () => 'TO',
-            }),
-          },
-        },
-        targets: [
-          {
-            refId: 'REFID',
-            name: 'STREAM',
-            querytext: 'QUERYTEXT',
-            collection: 'COLLECTION',
-            id: 'ID',
-          },
-        ],
-      } as unknown as DataQueryRequest<SdsQuery>;
+  describe('applyTemplateVariables', () => {
+    it('should replace variables in the query fields', () => {
+      const datasource = new DataSource(adhSettings);
+      const query: SdsQuery = {
+        ...defaultQuery,
+        refId: 'REFID',
+        collection: 'streams',
+        queryText: 'Name:$stream*',
+        id: '$stream',
+        name: '$stream',
+        startIndex: '$__from|$stream',
+        endIndex: '$__to|$stream',
+        targetUoms: ['$stream'],
+        orderBy: '$stream desc',
+        interval: '$stream',
+        tenantId: '$stream',
+        namespaceId: '$namespace',
+      };
+
+      expect(datasource.applyTemplateVariables(query, {})).toEqual({
+        ...query,
+        queryText: 'Name:Id1*',
+        id: 'Id1',
+        name: 'Id1',
+        startIndex: '$__from|Id1',
+        endIndex: '$__to|Id1',
+        targetUoms: ['Id1'],
+        orderBy: 'Id1 desc',
+        interval: 'Id1',
+        tenantId: 'Id1',
+        namespaceId: 'NAMESPACE2',
+      });
+    });
+  });
 
+  describe('metricFindQuery', () => {
+    it('should search every page of the streams matching the query', async () => {
       const datasource = new DataSource(adhSettings);
 
-      const results = datasource.queryEDS(options);
-
-      results.subscribe({
-        next(result) {
-          expect(JSON.stringify(result)).toEqual(
-            JSON.stringify({
-              data: [
-                new MutableDataFrame({
-                  refId: 'REFID',
-                  name: 'STREAM',
-                  fields: [
-                    {
-                      name: 'TimeStamp',
-                      type: FieldType.time,
-                      values: [Date.parse('2020-01-01')],
-                    },
-                    {
-                      name: 'Boolean',
-                      type: FieldType.number,
-                      values: [1],
-                    },
-                    {
-                      name: 'Number',
-                      type: FieldType.number,
-                      values: [1],
-                    },
-                    {
-                      name: 'String',
-                      type: FieldType.string,
-                      values: ['A'],
-                    },
-                  ],
-                }),
-              ],
-            })
-          );
-          done();
-        },
+      datasource.query = jest.fn(() => {
+        return new Observable((subscriber) => {
+          subscriber.next({
+            data: [
+              new MutableDataFrame({
+                refId: 'sds-variable-query',
+                fields: [
+                  { name: 'Id', type: FieldType.string, values: ['Id1', 'Id2'] },
+                  { name: 'Name', type: FieldType.string, values: ['Name1', 'Name2'] },
+                ],
+              }),
+            ],
+          });
+
+          subscriber.complete();
+        });
       });
+
+      const results = await datasource.metricFindQuery('Name:$stream*');
+
+      const request = (datasource.query as jest.Mock).mock.calls[0][0] as DataQueryRequest<SdsQuery>;
+      expect(request.targets[0]).toMatchObject({ queryText: 'Name:Id1*', collection: 'streams', allPages: true });
+      expect(results).toEqual([
+        { text: 'Name1', value: 'Id1' },
+        { text: 'Name2', value: 'Id2' },
+      ]);
     });
   });
 
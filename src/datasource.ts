@@ -1,100 +1,83 @@
-import { DataSourceInstanceSettings, DataQueryRequest, DataQueryResponse, FieldType, MutableDataFrame, DataFrame, SelectableValue } from '@grafana/data';
-import { DataSourceWithBackend, FetchResponse, getBackendSrv } from '@grafana/runtime';
+import {
+  DataSourceInstanceSettings,
+  DataQueryRequest,
+  DataFrame,
+  MetricFindValue,
+  ScopedVars,
+  SelectableValue,
+} from '@grafana/data';
+import { DataSourceWithBackend, getTemplateSrv } from '@grafana/runtime';
 
 import { defaultQuery, SdsDataSourceOptions, SdsDataSourceType, SdsQuery } from './types';
-import { Observable, zip, map, lastValueFrom } from 'rxjs';
+import { lastValueFrom } from 'rxjs';
 import { Dispatch, SetStateAction } from 'react';
 
 export class DataSource extends DataSourceWithBackend<SdsQuery, SdsDataSourceOptions> {
   type: SdsDataSourceType;
-  edsPort: string;
+  useCommunity: boolean;
   constructor(instanceSettings: DataSourceInstanceSettings<SdsDataSourceOptions>) {
     super(instanceSettings);
     this.type = instanceSettings.jsonData?.type || SdsDataSourceType.ADH;
-    this.edsPort = instanceSettings.jsonData?.edsPort || '5590';
+    this.useCommunity = instanceSettings.jsonData?.useCommunity || false;
   }
 
-  queryEDS(request: DataQueryRequest<SdsQuery>): Observable<DataQueryResponse> {
-    const from = request.range.from.utc().format();
-    const to = request.range.to.utc().format();
-
-    const requests = request.targets.map((target) => {
-      if (target.id === '') {
-        return getBackendSrv().fetch({
-          url: `http://localhost:${this.edsPort}/api/v1/tenants/default/namespaces/default/streams?query=${target.queryText}`,
-          method: 'GET',
-        });
-      } else {
-        return getBackendSrv().fetch({
-          url: `http://localhost:${this.edsPort}/api/v1/tenants/default/namespaces/default/streams/${target.id}/data?startIndex=${from}&endIndex=${to}`,
-          method: 'GET',
-        });
-      }
-    });
-
-    return zip(requests).pipe(
-      map((responses) => {
-        let i = 0;
-        const data = responses.map((r: FetchResponse) => {
-          if (!r || !r.data.length) {
-            return new MutableDataFrame();
-          }
-
-          const target = request.targets[i];
-          i++;
-          return new MutableDataFrame({
-            refId: target.refId,
-            name: target.name,
-            fields: Object.keys(r.data[0]).map((name) => {
-              const val0 = r.data[0][name];
-              const date = Date.parse(val0);
-              const num = Number(val0);
-              const type =
-                typeof val0 === 'string' && !isNaN(date)
-                  ? FieldType.time
-                  : val0 === true || val0 === false
-                  ? FieldType.boolean
-                  : !isNaN(num)
-                  ? FieldType.number
-                  : FieldType.string;
-
-              let values = [];
-              if (type === FieldType.boolean) {
-                values = r.data.map((d: any) => {
-                  return d[name]?.toString().toLowerCase() === 'true' ? 1 : 0;
-                });
-              } else {
-                values = r.data.map((d: any) => (type === FieldType.time ? Date.parse(d[name]) : d[name]));
-              }
-
-              return {
-                name,
-                values: values,
-                type: type === FieldType.boolean ? FieldType.number : type,
-              };
-            }),
-          });
-        });
-
-        return { data };
-      })
-    );
+  applyTemplateVariables(query: SdsQuery, scopedVars: ScopedVars): SdsQuery {
+    const templateSrv = getTemplateSrv();
+    const replace = (value?: string) => templateSrv.replace(value || '', scopedVars);
+    return {
+      ...query,
+      queryText: replace(query.queryText),
+      id: replace(query.id),
+      name: replace(query.name),
+      startIndex: this.replaceIndex(query.startIndex, scopedVars),
+      endIndex: this.replaceIndex(query.endIndex, scopedVars),
+      targetUoms: query.targetUoms?.map((uom) => replace(uom)),
+      orderBy: replace(query.orderBy),
+      interval: replace(query.interval),
+      tenantId: replace(query.tenantId),
+      namespaceId: replace(query.namespaceId),
+    };
   }
 
-query(request: DataQueryRequest<SdsQuery>): Observable<DataQueryResponse> {
-    if (this.type === SdsDataSourceType.ADH) {
-      return super.query(request);
-    } else {
-      return this.queryEDS(request);
+  // $__from and $__to are left for the backend, which replaces them with timestamps of the
+  // index type, while the template service would replace them with epoch milliseconds
+  replaceIndex(index: string | undefined, scopedVars: ScopedVars): string {
+    return (index || '')
+      .split(/(\$__from|\$__to)/)
+      .map((part, i) => (i % 2 === 1 ? part : getTemplateSrv().replace(part, scopedVars)))
+      .join('');
+  }
+
+  async metricFindQuery(query: string, options?: any): Promise<MetricFindValue[]> {
+    // variables read every page of the stream search
+    const queryText = getTemplateSrv().replace(query, options?.scopedVars);
+    const observableResponse = this.query({
+      targets: [{ ...defaultQuery, refId: 'sds-variable-query', queryText, collection: 'streams', id: '', allPages: true }],
+      range: options?.range,
+    } as DataQueryRequest<SdsQuery>);
+
+    const response = await lastValueFrom(observableResponse);
+    const dataFrame = response?.data?.[0] as DataFrame;
+    const idField = dataFrame?.fields?.find((field) => field.name === 'Id');
+    const nameField = dataFrame?.fields?.find((field) => field.name === 'Name');
+    if (!idField || !nameField) {
+      return [];
     }
+
+    const ids = idField.values.toArray();
+    const names = nameField.values.toArray();
+    return ids.map((id: string, i: number) => ({ text: names[i], value: id }));
   }
 
   async getStreams(
     query: string,
-    stateAction: Dispatch<SetStateAction<boolean | Array<SelectableValue<string>>>>
+    stateAction: Dispatch<SetStateAction<boolean | Array<SelectableValue<string>>>>,
+    namespaceId?: string
   ): Promise<Array<SelectableValue<string>>> {
     const observableResponse = this.query({
-      targets: [{ ...defaultQuery, refId: 'sds-stream-autocomplete', queryText: query, collection: 'streams', id: '' }],
+      targets: [
+        { ...defaultQuery, refId: 'sds-stream-autocomplete', queryText: query, collection: 'streams', id: '', namespaceId },
+      ],
     } as DataQueryRequest<SdsQuery>);
 
     const response = await lastValueFrom(observableResponse);
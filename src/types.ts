@@ -11,6 +11,20 @@ export interface SdsQuery extends DataQuery {
   queryText: string;
   id: string;
   name: string;
+  booleansAsNumbers?: boolean;
+  startIndex?: string;
+  endIndex?: string;
+  targetUoms?: string[];
+  skip?: number;
+  count?: number;
+  orderBy?: string;
+  allPages?: boolean;
+  includeMetadata?: boolean;
+  includeLabels?: boolean;
+  interval?: string;
+  lastValue?: boolean;
+  tenantId?: string;
+  namespaceId?: string;
 }
 
 export const defaultQuery: Partial<SdsQuery> = {
@@ -18,10 +32,25 @@ export const defaultQuery: Partial<SdsQuery> = {
   queryText: '',
   id: '',
   name: '',
+  booleansAsNumbers: false,
+  startIndex: '',
+  endIndex: '',
+  targetUoms: [],
+  skip: 0,
+  count: 0,
+  orderBy: '',
+  allPages: false,
+  includeMetadata: false,
+  includeLabels: false,
+  interval: '',
+  lastValue: false,
+  tenantId: '',
+  namespaceId: '',
 };
 
 export interface SdsDataSourceOptions extends DataSourceJsonData {
   type: SdsDataSourceType;
+  edsHost?: string;
   edsPort: string;
   resource: string;
   apiVersion: string;
@@ -30,10 +59,20 @@ export interface SdsDataSourceOptions extends DataSourceJsonData {
   useCommunity: boolean;
   communityId: string;
   oauthPassThru: boolean;
+  clientCredentialsFallback?: boolean;
+  clientAuthMethod?: string;
+  scopes?: string;
+  audience?: string;
   namespaceId: string;
+  namespaceIds?: string[];
+  cacheTtl?: number;
+  cacheSize?: number;
+  metadataCacheTtl?: number;
+  incrementalQueries?: boolean;
 }
 
 export interface SdsDataSourceSecureOptions {
   clientSecret: string;
+  clientCertificate?: string;
 }
 